@@ -18,13 +18,16 @@ package main
 
 import (
 	"log"
+	"slices"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/tiny-giraffes/life-beacon-360/server/config"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/api"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/jobs"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/migrations"
-	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
 	"github.com/tiny-giraffes/life-beacon-360/server/pkg/database"
 )
 
@@ -35,11 +38,13 @@ func main() {
 	// Initialize Echo instance
 	e := echo.New()
 
-	// CORS middleware
+	// CORS middleware; cookies are only allowed for explicitly listed origins
+	allowOrigins := config.AppConfig.CORSAllowedOrigins
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{echo.GET, echo.POST, echo.OPTIONS},
-		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowOrigins:     allowOrigins,
+		AllowMethods:     []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE, echo.OPTIONS},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", auth.CSRFHeaderName},
+		AllowCredentials: !slices.Contains(allowOrigins, "*"),
 	}))
 
 	// Connect to the database
@@ -64,6 +69,9 @@ func main() {
 		log.Fatal("Failed to run database migrations:", err)
 	}
 
+	// Remove soft-deleted users and groups once their retention has passed
+	jobs.StartPurge(db, time.Duration(config.AppConfig.PurgeDeletedAfterDays)*24*time.Hour)
+
 	// Set up API routes
 	api.SetupRoutes(e, db)
 
@@ -18,10 +18,14 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Config struct {
@@ -30,7 +34,54 @@ type Config struct {
 	DBName     string
 	DBHost     string
 	DBPort     string
-	ApiToken   string
+
+	// Password hashing and policy
+	PasswordHashAlgorithm    string // "argon2id" or "bcrypt"
+	BcryptCost               int
+	Argon2Memory             int // KiB
+	Argon2Iterations         int
+	Argon2Parallelism        int
+	PasswordMinLength        int
+	PasswordRequireMixedCase bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+
+	// Web session cookies and CORS
+	SessionCookieSecure   bool
+	SessionCookieSameSite string // "strict", "lax" or "none"
+	SessionCookieDomain   string
+	CORSAllowedOrigins    []string
+
+	// Soft-deleted users and groups are purged for good after this many days (0 never purges)
+	PurgeDeletedAfterDays int
+
+	// Outgoing email; without SMTPHost only recipient and subject are logged
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	SMTPTLS      string // "none", "starttls" or "tls"
+	// Log whole unsent emails, including temporary passwords; development only
+	MailLogBodies bool
+
+	// Public URL of the web client, used for links in emails
+	AppBaseURL        string
+	InviteExpiryHours int
+
+	// Self-service password reset
+	PasswordResetExpiryMinutes int
+	PasswordResetMaxPerHour    int // requests per email address
+
+	// OpenID Connect single sign-on; disabled without OIDCIssuerURL
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string // optional for public clients, PKCE is always used
+	OIDCRedirectURL   string // the server's /api/auth/oidc/callback as registered with the IdP
+	OIDCScopes        []string
+	OIDCMatchClaim    string // "email" (verified email) or "sub"
+	OIDCAutoProvision bool
+	OIDCDefaultGroup  string // name of the group new users are put in; the default group when empty
 }
 
 var AppConfig Config
@@ -54,11 +105,149 @@ func LoadConfig() {
 		DBName:     os.Getenv("POSTGRES_DB"),
 		DBHost:     os.Getenv("POSTGRES_HOST"),
 		DBPort:     os.Getenv("POSTGRES_PORT"),
-		ApiToken:   os.Getenv("API_TOKEN"), // Load the API token
+
+		PasswordHashAlgorithm:    getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:               getEnvInt("PASSWORD_BCRYPT_COST", 12),
+		Argon2Memory:             getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+		Argon2Iterations:         getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:        getEnvInt("PASSWORD_ARGON2_PARALLELISM", 2),
+		PasswordMinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordRequireMixedCase: getEnvBool("PASSWORD_REQUIRE_MIXED_CASE", false),
+		PasswordRequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:    getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+
+		SessionCookieSecure:   getEnvBool("SESSION_COOKIE_SECURE", true),
+		SessionCookieSameSite: getEnv("SESSION_COOKIE_SAMESITE", "strict"),
+		SessionCookieDomain:   os.Getenv("SESSION_COOKIE_DOMAIN"),
+		CORSAllowedOrigins:    getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+
+		PurgeDeletedAfterDays: getEnvInt("PURGE_DELETED_AFTER_DAYS", 30),
+
+		SMTPHost:      os.Getenv("SMTP_HOST"),
+		SMTPPort:      getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:      getEnv("SMTP_FROM", "Life Beacon 360 <no-reply@localhost>"),
+		SMTPTLS:       getEnv("SMTP_TLS", "starttls"),
+		MailLogBodies: getEnvBool("MAIL_LOG_BODIES", false),
+
+		AppBaseURL:        strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:3000"), "/"),
+		InviteExpiryHours: getEnvInt("INVITE_EXPIRY_HOURS", 72),
+
+		PasswordResetExpiryMinutes: getEnvInt("PASSWORD_RESET_EXPIRY_MINUTES", 30),
+		PasswordResetMaxPerHour:    getEnvInt("PASSWORD_RESET_MAX_PER_HOUR", 3),
+
+		OIDCIssuerURL:     strings.TrimRight(getEnv("OIDC_ISSUER_URL", ""), "/"),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+		OIDCScopes:        getEnvList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		OIDCMatchClaim:    strings.ToLower(getEnv("OIDC_MATCH_CLAIM", "email")),
+		OIDCAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", true),
+		OIDCDefaultGroup:  getEnv("OIDC_DEFAULT_GROUP", ""),
+	}
+
+	if AppConfig.PasswordHashAlgorithm != "argon2id" && AppConfig.PasswordHashAlgorithm != "bcrypt" {
+		panic(fmt.Sprintf("Unsupported PASSWORD_HASH_ALGORITHM: %s", AppConfig.PasswordHashAlgorithm))
+	}
+
+	// Out-of-range hashing parameters would only fail (or panic) on the first
+	// login, so they are rejected at startup
+	if AppConfig.BcryptCost < bcrypt.MinCost || AppConfig.BcryptCost > bcrypt.MaxCost {
+		panic(fmt.Sprintf("Invalid PASSWORD_BCRYPT_COST: %d (must be %d-%d)", AppConfig.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if AppConfig.Argon2Iterations < 1 || int64(AppConfig.Argon2Iterations) > math.MaxUint32 {
+		panic(fmt.Sprintf("Invalid PASSWORD_ARGON2_ITERATIONS: %d (must be at least 1)", AppConfig.Argon2Iterations))
+	}
+	if AppConfig.Argon2Parallelism < 1 || AppConfig.Argon2Parallelism > math.MaxUint8 {
+		panic(fmt.Sprintf("Invalid PASSWORD_ARGON2_PARALLELISM: %d (must be 1-255)", AppConfig.Argon2Parallelism))
+	}
+	if AppConfig.Argon2Memory < 8*AppConfig.Argon2Parallelism || int64(AppConfig.Argon2Memory) > math.MaxUint32 {
+		panic(fmt.Sprintf("Invalid PASSWORD_ARGON2_MEMORY: %d KiB (must be at least 8 KiB per thread)", AppConfig.Argon2Memory))
+	}
+
+	if AppConfig.PurgeDeletedAfterDays < 0 {
+		panic(fmt.Sprintf("Invalid PURGE_DELETED_AFTER_DAYS: %d", AppConfig.PurgeDeletedAfterDays))
+	}
+
+	switch AppConfig.SMTPTLS {
+	case "none", "starttls", "tls":
+	default:
+		panic(fmt.Sprintf("Unsupported SMTP_TLS: %s", AppConfig.SMTPTLS))
 	}
 
-	// Ensure the API token is set, otherwise panic
-	if AppConfig.ApiToken == "" {
-		panic("API_TOKEN not set in .env file")
+	if AppConfig.InviteExpiryHours < 1 {
+		panic(fmt.Sprintf("Invalid INVITE_EXPIRY_HOURS: %d", AppConfig.InviteExpiryHours))
+	}
+
+	if AppConfig.PasswordResetExpiryMinutes < 1 {
+		panic(fmt.Sprintf("Invalid PASSWORD_RESET_EXPIRY_MINUTES: %d", AppConfig.PasswordResetExpiryMinutes))
+	}
+	if AppConfig.PasswordResetMaxPerHour < 1 {
+		panic(fmt.Sprintf("Invalid PASSWORD_RESET_MAX_PER_HOUR: %d", AppConfig.PasswordResetMaxPerHour))
+	}
+
+	if AppConfig.OIDCIssuerURL != "" {
+		if AppConfig.OIDCClientID == "" {
+			panic("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+		}
+		if AppConfig.OIDCMatchClaim != "email" && AppConfig.OIDCMatchClaim != "sub" {
+			panic(fmt.Sprintf("Invalid OIDC_MATCH_CLAIM: %s", AppConfig.OIDCMatchClaim))
+		}
+	}
+
+	switch AppConfig.SessionCookieSameSite {
+	case "strict", "lax", "none":
+	default:
+		panic(fmt.Sprintf("Unsupported SESSION_COOKIE_SAMESITE: %s", AppConfig.SessionCookieSameSite))
+	}
+}
+
+// getEnv returns the value of an environment variable or a default if it is unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt returns an integer environment variable or a default if it is unset
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid integer value for %s: %v", key, err))
+	}
+	return n
+}
+
+// getEnvBool returns a boolean environment variable or a default if it is unset
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid boolean value for %s: %v", key, err))
+	}
+	return b
+}
+
+// getEnvList returns a comma-separated environment variable as a list or a default if it is unset
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
@@ -22,14 +22,120 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/handlers"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/oidc"
 	"gorm.io/gorm"
 )
 
 // SetupRoutes sets up all the routes for the API
 func SetupRoutes(e *echo.Echo, db *gorm.DB) {
-	api := e.Group("/api")
+	api := e.Group("/api", middleware.AuditContext)
+	auth := middleware.AuthMiddleware(db)
 
-	// Location routes
-	api.POST("/locations", handlers.CreateLocation(db), middleware.AuthMiddleware)
-	api.GET("/locations", handlers.GetLatestLocations(db), middleware.AuthMiddleware)
+	// Setup routes
+	api.GET("/setup/status", handlers.GetSetupStatus(db))
+	api.POST("/setup", handlers.RunSetup(db))
+
+	// Auth routes
+	api.POST("/auth/login", handlers.Login(db))
+	api.POST("/auth/login/2fa", handlers.LoginTwoFactor(db))
+	api.POST("/auth/login/2fa/setup", handlers.LoginTwoFactorSetup(db))
+	api.POST("/auth/logout", handlers.Logout(db), auth)
+	api.POST("/auth/password", handlers.ChangePassword(db), auth)
+	api.POST("/auth/accept-invite", handlers.AcceptInvite(db))
+	api.POST("/auth/password-reset/request", handlers.RequestPasswordReset(db))
+	api.POST("/auth/password-reset/confirm", handlers.ConfirmPasswordReset(db))
+
+	// Single sign-on routes; login and callback only exist when configured
+	oidcProvider := oidc.FromConfig()
+	api.GET("/auth/oidc", handlers.GetOIDCStatus(oidcProvider))
+	if oidcProvider != nil {
+		api.GET("/auth/oidc/login", handlers.OIDCLogin(db, oidcProvider))
+		api.GET("/auth/oidc/callback", handlers.OIDCCallback(db, oidcProvider))
+	}
+
+	// Two-factor authentication routes
+	api.GET("/auth/2fa", handlers.GetTwoFactorStatus(db), auth)
+	api.POST("/auth/2fa/setup", handlers.SetupTwoFactor(db), auth)
+	api.POST("/auth/2fa/enable", handlers.EnableTwoFactor(db), auth)
+	api.POST("/auth/2fa/disable", handlers.DisableTwoFactor(db), auth)
+	api.POST("/auth/2fa/recovery-codes", handlers.RegenerateRecoveryCodes(db), auth)
+	api.DELETE("/users/:id/2fa", handlers.ResetUserTwoFactor(db), auth, middleware.RequireAdmin)
+
+	// Login lockout routes
+	api.GET("/lockouts", handlers.ListLockouts(db), auth, middleware.RequireAdmin)
+	api.DELETE("/lockouts/ip/:ip", handlers.UnlockIP(db), auth, middleware.RequireAdmin)
+	api.POST("/users/:id/unlock", handlers.UnlockUser(db), auth, middleware.RequireAdmin)
+
+	// Session routes
+	api.GET("/sessions", handlers.ListMySessions(db), auth)
+	api.POST("/sessions/revoke-others", handlers.RevokeOtherSessions(db), auth)
+	api.GET("/sessions/:id", handlers.GetSession(db), auth)
+	api.DELETE("/sessions/:id", handlers.RevokeSession(db), auth)
+	api.GET("/users/:id/sessions", handlers.ListUserSessions(db), auth, middleware.RequireAdmin)
+	api.DELETE("/users/:id/sessions", handlers.RevokeUserSessions(db), auth, middleware.RequireAdmin)
+
+	// API key routes
+	api.GET("/api-keys", handlers.ListMyAPIKeys(db), auth)
+	api.POST("/api-keys", handlers.CreateMyAPIKey(db), auth)
+	api.DELETE("/api-keys/:id", handlers.RevokeAPIKey(db), auth)
+	api.GET("/users/:id/api-keys", handlers.ListUserAPIKeys(db), auth, middleware.RequireAdmin)
+	api.POST("/users/:id/api-keys", handlers.CreateUserAPIKey(db), auth, middleware.RequireAdmin)
+
+	// Permission routes (admins only; users can never modify permissions)
+	api.GET("/permissions", handlers.ListPermissions(db), auth, middleware.RequireAdmin)
+	api.POST("/permissions", handlers.GrantPermission(db), auth, middleware.RequireAdmin)
+	api.DELETE("/permissions/:id", handlers.RevokePermission(db), auth, middleware.RequireAdmin)
+	api.DELETE("/users/:id/permissions", handlers.ResetUserPermissions(db), auth, middleware.RequireAdmin)
+	api.DELETE("/groups/:id/permissions", handlers.ResetGroupPermissions(db), auth, middleware.RequireAdmin)
+	api.PUT("/users/:id/role", handlers.UpdateUserRole(db), auth, middleware.RequireAdmin)
+
+	// User routes
+	manageUser := middleware.RequirePermissionOn(db, models.PermissionManageUsers, "id")
+	api.GET("/users", handlers.ListUsers(db), auth, middleware.RequirePermission(db, models.PermissionManageUsers))
+	api.POST("/users", handlers.CreateUser(db), auth, middleware.RequirePermission(db, models.PermissionManageUsers))
+	api.GET("/users/:id", handlers.GetUser(db), auth, manageUser)
+	api.PATCH("/users/:id", handlers.UpdateUser(db), auth, manageUser)
+	api.DELETE("/users/:id", handlers.DeleteUser(db), auth, manageUser)
+	api.POST("/users/:id/restore", handlers.RestoreUser(db), auth, middleware.RequireAdmin)
+	api.POST("/users/:id/invite", handlers.ResendInvitation(db), auth, manageUser)
+
+	// Group routes
+	manageGroups := middleware.RequirePermission(db, models.PermissionManageGroups)
+	api.GET("/groups", handlers.ListGroups(db), auth, manageGroups)
+	api.POST("/groups", handlers.CreateGroup(db), auth, manageGroups)
+	api.GET("/groups/:id", handlers.GetGroup(db), auth, manageGroups)
+	api.PATCH("/groups/:id", handlers.RenameGroup(db), auth, manageGroups)
+	api.DELETE("/groups/:id", handlers.DeleteGroup(db), auth, manageGroups)
+	api.POST("/groups/:id/restore", handlers.RestoreGroup(db), auth, middleware.RequireAdmin)
+	api.POST("/groups/:id/members", handlers.MoveUsersToGroup(db), auth, manageGroups)
+
+	// Settings routes
+	api.GET("/settings/global", handlers.GetGlobalSettings(db), auth, middleware.RequireAdmin)
+	api.PATCH("/settings/global", handlers.UpdateGlobalSettings(db), auth, middleware.RequireAdmin)
+	api.GET("/groups/:id/settings", handlers.GetGroupSettings(db), auth, middleware.RequireAdmin)
+	api.PATCH("/groups/:id/settings", handlers.UpdateGroupSettings(db), auth, middleware.RequireAdmin)
+	api.DELETE("/groups/:id/settings", handlers.ClearGroupSettings(db), auth, middleware.RequireAdmin)
+	api.DELETE("/groups/:id/settings/:key", handlers.ClearGroupSettings(db), auth, middleware.RequireAdmin)
+	api.GET("/users/:id/settings", handlers.GetUserSettings(db), auth, middleware.RequireAdmin)
+	api.PATCH("/users/:id/settings", handlers.UpdateUserSettings(db), auth, middleware.RequireAdmin)
+	api.DELETE("/users/:id/settings", handlers.ClearUserSettings(db), auth, middleware.RequireAdmin)
+	api.DELETE("/users/:id/settings/:key", handlers.ClearUserSettings(db), auth, middleware.RequireAdmin)
+	api.GET("/users/:id/settings/effective", handlers.GetEffectiveSettings(db), auth)
+
+	// Tracking routes
+	api.GET("/users/me/tracking-config", handlers.GetTrackingConfig(db), auth)
+	api.POST("/users/:id/tracking", handlers.ControlTracking(db), auth)
+	api.GET("/users/:id/tracking/log", handlers.ListTrackingControlLog(db), auth,
+		middleware.RequirePermissionOn(db, models.PermissionControlTracking, "id"))
+	api.PUT("/users/:id/tracking/self-control", handlers.SetSelfTrackingControl(db), auth, middleware.RequireAdmin)
+
+	// Audit routes
+	api.GET("/audit", handlers.ListAuditLogs(db), auth, middleware.RequireAdmin)
+	api.GET("/audit/export", handlers.ExportAuditLogs(db), auth, middleware.RequireAdmin)
+
+	// Location routes; devices may use API keys with a matching scope
+	api.POST("/locations", handlers.CreateLocation(db), middleware.AuthMiddleware(db, models.ScopeLocationsWrite))
+	api.POST("/locations/batch", handlers.CreateLocationBatch(db), middleware.AuthMiddleware(db, models.ScopeLocationsWrite))
+	api.GET("/locations", handlers.GetLatestLocations(db), middleware.AuthMiddleware(db, models.ScopeLocationsReadSelf))
 }
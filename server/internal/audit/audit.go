@@ -0,0 +1,170 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/audit/audit.go
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionSetupComplete      = "setup.complete"
+	ActionUserCreate         = "user.create"
+	ActionUserUpdate         = "user.update"
+	ActionUserDelete         = "user.delete"
+	ActionUserRestore        = "user.restore"
+	ActionUserPurge          = "user.purge"
+	ActionUserInvite         = "user.invite"
+	ActionUserInviteAccept   = "user.invite_accept"
+	ActionUserRoleChange     = "user.role_change"
+	ActionUserPasswordChange = "user.password_change"
+	ActionUserPasswordReset  = "user.password_reset"
+	ActionUser2FAEnable      = "user.2fa_enable"
+	ActionUser2FADisable     = "user.2fa_disable"
+	ActionUser2FAReset       = "user.2fa_reset"
+	ActionUser2FARecovery    = "user.2fa_recovery_codes"
+	ActionUser2FARecoveryUse = "user.2fa_recovery_use"
+	ActionUserOIDCLink       = "user.oidc_link"
+	ActionGroupCreate        = "group.create"
+	ActionGroupUpdate        = "group.update"
+	ActionGroupDelete        = "group.delete"
+	ActionGroupRestore       = "group.restore"
+	ActionGroupPurge         = "group.purge"
+	ActionGroupMoveUsers     = "group.move_users"
+	ActionPermissionGrant    = "permission.grant"
+	ActionPermissionRevoke   = "permission.revoke"
+	ActionPermissionReset    = "permission.reset"
+	ActionSessionRevoke      = "session.revoke"
+	ActionSessionRevokeAll   = "session.revoke_all"
+	ActionAPIKeyCreate       = "api_key.create"
+	ActionAPIKeyRevoke       = "api_key.revoke"
+	ActionSettingsUpdate     = "settings.update"
+	ActionLoginLockout       = "login.lockout"
+	ActionLoginUnlock        = "login.unlock"
+	ActionTrackingStart      = "tracking.start"
+	ActionTrackingStop       = "tracking.stop"
+)
+
+// Entity types recorded in the audit log
+const (
+	EntityUser       = "user"
+	EntityGroup      = "group"
+	EntityPermission = "permission"
+	EntitySession    = "session"
+	EntityAPIKey     = "api_key"
+
+	EntityGlobalSettings = "global_settings"
+	EntityGroupSettings  = "group_settings" // entity ID is the group ID
+	EntityUserSettings   = "user_settings"  // entity ID is the user ID
+	EntityTracking       = "tracking"       // entity ID is the tracked user's ID
+	EntityLoginThrottle  = "login_throttle" // entity ID is the user's ID for account lockouts
+)
+
+// Actor identifies who performed a change and from where
+type Actor struct {
+	UserID    *uuid.UUID
+	IPAddress string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the actor of the current request
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in the context, or an empty actor for
+// changes not made on behalf of a request (e.g. background jobs)
+func ActorFrom(ctx context.Context) Actor {
+	if ctx == nil {
+		return Actor{}
+	}
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// Changes is the before/after payload of an audit entry
+type Changes struct {
+	Before map[string]interface{} `json:"before,omitempty"`
+	After  map[string]interface{} `json:"after,omitempty"`
+}
+
+// Diff builds the changes between two states of an entity. Either side may be
+// nil for creations and deletions; for updates only changed fields are kept.
+// Values are compared in their JSON form, so fields hidden from JSON (such as
+// password hashes) never end up in the log.
+func Diff(before, after interface{}) (Changes, error) {
+	beforeMap, err := toMap(before)
+	if err != nil {
+		return Changes{}, err
+	}
+	afterMap, err := toMap(after)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	if beforeMap == nil || afterMap == nil {
+		return Changes{Before: beforeMap, After: afterMap}, nil
+	}
+
+	changes := Changes{
+		Before: map[string]interface{}{},
+		After:  map[string]interface{}{},
+	}
+	for key, value := range afterMap {
+		if old, ok := beforeMap[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes.Before[key] = beforeMap[key]
+			changes.After[key] = value
+		}
+	}
+	for key, value := range beforeMap {
+		if _, ok := afterMap[key]; !ok {
+			changes.Before[key] = value
+			changes.After[key] = nil
+		}
+	}
+	return changes, nil
+}
+
+func toMap(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
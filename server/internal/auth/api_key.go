@@ -0,0 +1,48 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/auth/api_key.go
+
+package auth
+
+import (
+	"strings"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+)
+
+// apiKeyIDLength is the number of random bytes in the public part of a key
+const apiKeyIDLength = 4
+
+// GenerateAPIKey returns a new API key in the form lb360_<id>_<secret>
+// together with its lb360_<id> prefix, which is shown to identify the key
+func GenerateAPIKey() (key, prefix string, err error) {
+	id, err := models.GenerateToken(apiKeyIDLength)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := models.GenerateToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	prefix = models.APIKeyTokenPrefix + id
+	return prefix + "_" + secret, prefix, nil
+}
+
+// IsAPIKey checks if a bearer token is an API key rather than a session token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, models.APIKeyTokenPrefix)
+}
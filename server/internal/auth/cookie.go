@@ -0,0 +1,120 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/auth/cookie.go
+
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/config"
+)
+
+const (
+	// SessionCookieName holds the session token of web clients (httpOnly)
+	SessionCookieName = "lb360_session"
+	// CSRFCookieName holds the CSRF token so the web client can read it back after a reload
+	CSRFCookieName = "lb360_csrf"
+	// CSRFHeaderName is the header web clients echo the CSRF token in
+	CSRFHeaderName = "X-CSRF-Token"
+	// OIDCStateCookieName binds a single sign-on login to the browser that started it
+	OIDCStateCookieName = "lb360_oidc_state"
+
+	oidcStateCookiePath = "/api/auth/oidc"
+)
+
+// SetSessionCookies issues the session and CSRF cookies for a web session
+func SetSessionCookies(c echo.Context, token, csrfToken string, expiresAt time.Time) {
+	c.SetCookie(newCookie(SessionCookieName, token, expiresAt, true))
+	c.SetCookie(newCookie(CSRFCookieName, csrfToken, expiresAt, false))
+}
+
+// ClearSessionCookies expires the session and CSRF cookies
+func ClearSessionCookies(c echo.Context) {
+	expired := time.Unix(0, 0)
+	for _, name := range []string{SessionCookieName, CSRFCookieName} {
+		cookie := newCookie(name, "", expired, name == SessionCookieName)
+		cookie.MaxAge = -1
+		c.SetCookie(cookie)
+	}
+}
+
+// SetOIDCStateCookie remembers the state of a single sign-on login in the
+// browser. The identity provider redirects back with a cross-site navigation,
+// so the cookie is sent with SameSite=Lax at most.
+func SetOIDCStateCookie(c echo.Context, state string, expiresAt time.Time) {
+	cookie := newCookie(OIDCStateCookieName, state, expiresAt, true)
+	cookie.Path = oidcStateCookiePath
+	if cookie.SameSite == http.SameSiteStrictMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	c.SetCookie(cookie)
+}
+
+// ClearOIDCStateCookie expires the single sign-on state cookie
+func ClearOIDCStateCookie(c echo.Context) {
+	cookie := newCookie(OIDCStateCookieName, "", time.Unix(0, 0), true)
+	cookie.Path = oidcStateCookiePath
+	cookie.MaxAge = -1
+	if cookie.SameSite == http.SameSiteStrictMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	c.SetCookie(cookie)
+}
+
+// ValidCSRFToken compares the CSRF token sent by the client with the session's in constant time
+func ValidCSRFToken(expected, actual string) bool {
+	if expected == "" || actual == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
+
+// IsStateChangingMethod reports whether requests with this method need CSRF protection
+func IsStateChangingMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+func newCookie(name, value string, expiresAt time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   config.AppConfig.SessionCookieDomain,
+		Expires:  expiresAt,
+		HttpOnly: httpOnly,
+		Secure:   config.AppConfig.SessionCookieSecure,
+		SameSite: sameSiteMode(),
+	}
+}
+
+func sameSiteMode() http.SameSite {
+	switch config.AppConfig.SessionCookieSameSite {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteStrictMode
+}
@@ -0,0 +1,90 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/auth/lockout.go
+
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// LockoutPolicy holds the brute-force protection thresholds from the global
+// settings
+type LockoutPolicy struct {
+	AccountThreshold int
+	IPThreshold      int
+	Duration         time.Duration // also the window failures are counted in
+}
+
+// LoadLockoutPolicy reads the lockout settings
+func LoadLockoutPolicy(db *gorm.DB) (LockoutPolicy, error) {
+	global, err := repository.GetGlobalSettings(db)
+	if err != nil {
+		return LockoutPolicy{}, err
+	}
+	return LockoutPolicy{
+		AccountThreshold: global.LockoutThreshold,
+		IPThreshold:      global.IPLockoutThreshold,
+		Duration:         time.Duration(global.LockoutDurationMinutes) * time.Minute,
+	}, nil
+}
+
+// AccountThrottleKey is the throttle key of a username. Unknown usernames are
+// tracked the same way, so lockouts do not reveal which accounts exist.
+func AccountThrottleKey(username string) string {
+	return models.ThrottleKeyAccount + strings.ToLower(strings.TrimSpace(username))
+}
+
+// IPThrottleKey is the throttle key of a client IP address
+func IPThrottleKey(ip string) string {
+	return models.ThrottleKeyIP + ip
+}
+
+// LoginRetryAt returns when the next login attempt for a throttle is allowed.
+// Besides lockouts, account keys back off exponentially from the second
+// failure on: 1s, 2s, 4s and so on, up to the lockout duration.
+func (p LockoutPolicy) LoginRetryAt(throttle *models.LoginThrottle, now time.Time) (time.Time, bool) {
+	if throttle.IsLocked(now) {
+		return *throttle.LockedUntil, true
+	}
+	if !strings.HasPrefix(throttle.Key, models.ThrottleKeyAccount) || throttle.Failures < 2 {
+		return time.Time{}, false
+	}
+	if throttle.LastFailureAt.Before(now.Add(-p.Duration)) {
+		return time.Time{}, false
+	}
+
+	backoff := p.Duration
+	if shift := throttle.Failures - 2; shift < 30 {
+		backoff = min(time.Duration(1<<shift)*time.Second, p.Duration)
+	}
+	retryAt := throttle.LastFailureAt.Add(backoff)
+	return retryAt, now.Before(retryAt)
+}
+
+// Threshold returns the number of failures that locks out a throttle key
+func (p LockoutPolicy) Threshold(key string) int {
+	if strings.HasPrefix(key, models.ThrottleKeyIP) {
+		return p.IPThreshold
+	}
+	return p.AccountThreshold
+}
@@ -0,0 +1,297 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/auth/password.go
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+
+	// bcrypt silently ignores everything past 72 bytes
+	bcryptMaxPasswordLength = 72
+	maxPasswordLength       = 128
+)
+
+var (
+	ErrInvalidHash         = errors.New("invalid password hash format")
+	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+)
+
+// PolicyError describes why a password was rejected by the password policy
+type PolicyError struct {
+	Reasons []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet policy: " + strings.Join(e.Reasons, "; ")
+}
+
+// argon2Params holds the parameters encoded in an argon2id hash
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// currentArgon2Params returns the argon2id parameters from the configuration
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		memory:      uint32(config.AppConfig.Argon2Memory),
+		iterations:  uint32(config.AppConfig.Argon2Iterations),
+		parallelism: uint8(config.AppConfig.Argon2Parallelism),
+	}
+}
+
+// HashPassword hashes a password with the configured algorithm
+func HashPassword(password string) (string, error) {
+	if config.AppConfig.PasswordHashAlgorithm == "bcrypt" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), config.AppConfig.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	return hashArgon2(password, currentArgon2Params())
+}
+
+// VerifyPassword checks a password against a stored hash in constant time.
+// needsRehash reports whether the hash was produced with an algorithm or
+// parameters that differ from the current configuration.
+func VerifyPassword(encodedHash, password string) (ok bool, needsRehash bool, err error) {
+	if strings.HasPrefix(encodedHash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2(encodedHash)
+		if err != nil {
+			return false, false, err
+		}
+
+		candidate := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, candidate) != 1 {
+			return false, false, nil
+		}
+
+		needsRehash = config.AppConfig.PasswordHashAlgorithm != "argon2id" || params != currentArgon2Params()
+		return true, needsRehash, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return false, false, err
+	}
+	needsRehash = config.AppConfig.PasswordHashAlgorithm != "bcrypt" || cost != config.AppConfig.BcryptCost
+	return true, needsRehash, nil
+}
+
+// dummyHash is verified against when a login names an unknown user so that
+// the response time does not reveal whether the account exists
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// EqualizeTiming performs a throwaway verification to mask a missing account
+func EqualizeTiming(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("life-beacon-360-dummy-password")
+	})
+	_, _, _ = VerifyPassword(dummyHash, password)
+}
+
+// ValidatePassword checks a password against the configured password policy
+func ValidatePassword(password string) error {
+	var reasons []string
+
+	maxLength := maxPasswordLength
+	if config.AppConfig.PasswordHashAlgorithm == "bcrypt" {
+		maxLength = bcryptMaxPasswordLength
+	}
+
+	if len([]rune(password)) < config.AppConfig.PasswordMinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters long", config.AppConfig.PasswordMinLength))
+	}
+	if len(password) > maxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d bytes long", maxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if config.AppConfig.PasswordRequireMixedCase && !(hasUpper && hasLower) {
+		reasons = append(reasons, "must contain both upper and lower case letters")
+	}
+	if config.AppConfig.PasswordRequireDigit && !hasDigit {
+		reasons = append(reasons, "must contain a digit")
+	}
+	if config.AppConfig.PasswordRequireSymbol && !hasSymbol {
+		reasons = append(reasons, "must contain a symbol")
+	}
+
+	if len(reasons) > 0 {
+		return &PolicyError{Reasons: reasons}
+	}
+	return nil
+}
+
+// Character classes for generated passwords; look-alike characters are left out
+var temporaryPasswordClasses = []string{
+	"ABCDEFGHJKLMNPQRSTUVWXYZ",
+	"abcdefghijkmnopqrstuvwxyz",
+	"23456789",
+	"!@#$%*-_+=?",
+}
+
+// minTemporaryPasswordLength is the length of generated passwords unless the
+// policy asks for longer ones
+const minTemporaryPasswordLength = 16
+
+// GenerateTemporaryPassword creates a random password that satisfies the
+// password policy, containing at least one character of every class
+func GenerateTemporaryPassword() (string, error) {
+	length := max(minTemporaryPasswordLength, config.AppConfig.PasswordMinLength)
+	all := strings.Join(temporaryPasswordClasses, "")
+
+	password := make([]byte, 0, length)
+	for _, class := range temporaryPasswordClasses {
+		c, err := randomChar(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Shuffle so the guaranteed characters are not always up front
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomChar(chars string) (byte, error) {
+	i, err := randomIndex(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// hashArgon2 hashes a password with argon2id and encodes it in PHC string format
+func hashArgon2(password string, params argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2 parses an argon2id hash in PHC string format
+func decodeArgon2(encodedHash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, ErrIncompatibleVersion
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, key, nil
+}
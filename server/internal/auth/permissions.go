@@ -0,0 +1,115 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/auth/permissions.go
+
+package auth
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Sources a permission decision can come from
+const (
+	DecisionSourceAdmin = "admin"
+	DecisionSourceUser  = "user"
+	DecisionSourceGroup = "group"
+	DecisionSourceNone  = "none"
+)
+
+// Decision is the outcome of a permission check
+type Decision struct {
+	Allowed    bool
+	Source     string
+	Permission *models.Permission // the grant that allowed the action, if any
+}
+
+// ResolvePermission decides whether a user may perform an action on a target
+// user. Admins are always allowed; otherwise grants made to the user are
+// checked before grants made to the user's group, and anything else is denied.
+// A nil target asks whether the user holds the permission for any target.
+func ResolvePermission(db *gorm.DB, user *models.User, permissionType string, target *models.User) (Decision, error) {
+	if user.IsAdmin() {
+		return Decision{Allowed: true, Source: DecisionSourceAdmin}, nil
+	}
+
+	userPermissions, err := repository.ListUserPermissions(db, user.ID, permissionType)
+	if err != nil {
+		return Decision{}, err
+	}
+	if p := firstApplicable(userPermissions, user, target); p != nil {
+		return Decision{Allowed: true, Source: DecisionSourceUser, Permission: p}, nil
+	}
+
+	groupPermissions, err := repository.ListGroupPermissions(db, user.GroupID, permissionType)
+	if err != nil {
+		return Decision{}, err
+	}
+	if p := firstApplicable(groupPermissions, user, target); p != nil {
+		return Decision{Allowed: true, Source: DecisionSourceGroup, Permission: p}, nil
+	}
+
+	return Decision{Allowed: false, Source: DecisionSourceNone}, nil
+}
+
+// HasPermission is a shorthand for ResolvePermission that only reports the outcome
+func HasPermission(db *gorm.DB, user *models.User, permissionType string, target *models.User) (bool, error) {
+	decision, err := ResolvePermission(db, user, permissionType, target)
+	return decision.Allowed, err
+}
+
+// ResolveScope collects the users a permission type covers for a user, from
+// grants made to the user and to the user's group. Admins cover everyone.
+func ResolveScope(db *gorm.DB, user *models.User, permissionType string) (models.PermissionScope, error) {
+	if user.IsAdmin() {
+		return models.PermissionScope{All: true}, nil
+	}
+
+	userPermissions, err := repository.ListUserPermissions(db, user.ID, permissionType)
+	if err != nil {
+		return models.PermissionScope{}, err
+	}
+	groupPermissions, err := repository.ListGroupPermissions(db, user.GroupID, permissionType)
+	if err != nil {
+		return models.PermissionScope{}, err
+	}
+
+	var scope models.PermissionScope
+	for _, p := range append(userPermissions, groupPermissions...) {
+		switch p.TargetType {
+		case models.TargetAll:
+			return models.PermissionScope{All: true}, nil
+		case models.TargetSelf:
+			scope.UserIDs = append(scope.UserIDs, user.ID)
+		case models.TargetGroup:
+			scope.GroupIDs = append(scope.GroupIDs, user.GroupID)
+		case models.TargetSpecificUsers:
+			scope.UserIDs = append(scope.UserIDs, p.TargetUsers...)
+		}
+	}
+	return scope, nil
+}
+
+func firstApplicable(permissions []models.Permission, grantee *models.User, target *models.User) *models.Permission {
+	for i := range permissions {
+		if permissions[i].AppliesTo(grantee, target) {
+			return &permissions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/auth/session.go
+
+package auth
+
+import (
+	"time"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/settings"
+	"gorm.io/gorm"
+)
+
+// SessionLifetime returns the absolute maximum duration of a session and the
+// inactivity window that every authenticated request extends, as resolved
+// from the user's settings hierarchy
+func SessionLifetime(db *gorm.DB, user *models.User) (maxDuration, activityExtension time.Duration, err error) {
+	effective, err := settings.Resolve(db, user)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	day := 24 * time.Hour
+	return time.Duration(effective.Int(settings.KeySessionMaxDurationDays)) * day,
+		time.Duration(effective.Int(settings.KeySessionActivityExtensionDays)) * day,
+		nil
+}
+
+// IsSessionExpired reports whether a session has outlived its lifetime
+func IsSessionExpired(db *gorm.DB, session *models.Session, now time.Time) (bool, error) {
+	maxDuration, activityExtension, err := SessionLifetime(db, &session.User)
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(session.ExpiresAt(maxDuration, activityExtension)), nil
+}
@@ -0,0 +1,267 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/api_keys.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxAPIKeyNameLength is the longest API key name accepted
+const maxAPIKeyNameLength = 100
+
+// ListMyAPIKeys godoc
+// @Summary List own API keys
+// @Description Lists the API keys of the authenticated user, including revoked and expired keys
+// @Tags API Keys
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/api-keys [get]
+func ListMyAPIKeys(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return listAPIKeys(c, db, middleware.CurrentUser(c).ID)
+	}
+}
+
+// CreateMyAPIKey godoc
+// @Summary Create API key
+// @Description Creates an API key for a device of the authenticated user. The key is only returned once.
+// @Tags API Keys
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param key body models.CreateAPIKeyRequest true "Name, scopes and optional expiry"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/api-keys [post]
+func CreateMyAPIKey(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return createAPIKey(c, db, middleware.CurrentUser(c).ID)
+	}
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revokes an API key so it is no longer accepted. Users can revoke their own keys, admins any key.
+// @Tags API Keys
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/api-keys/{id} [delete]
+func RevokeAPIKey(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		keyID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid API key ID",
+			})
+		}
+
+		key, err := repository.GetAPIKeyByID(db, keyID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve API key",
+			})
+		}
+
+		// Other users' keys are reported as missing rather than forbidden
+		user := middleware.CurrentUser(c)
+		if key == nil || (key.UserID != user.ID && !user.IsAdmin()) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "API key not found",
+			})
+		}
+
+		if err := repository.RevokeAPIKey(requestDB(c, db), key.ID, user.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to revoke API key",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "API key revoked successfully",
+		})
+	}
+}
+
+// ListUserAPIKeys godoc
+// @Summary List user API keys
+// @Description Lists the API keys of any user (admin only)
+// @Tags API Keys
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} models.APIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/api-keys [get]
+func ListUserAPIKeys(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok, err := apiKeyOwner(c, db)
+		if !ok {
+			return err
+		}
+		return listAPIKeys(c, db, user.ID)
+	}
+}
+
+// CreateUserAPIKey godoc
+// @Summary Create user API key
+// @Description Creates an API key for a device of any user (admin only). The key is only returned once.
+// @Tags API Keys
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param key body models.CreateAPIKeyRequest true "Name, scopes and optional expiry"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/api-keys [post]
+func CreateUserAPIKey(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok, err := apiKeyOwner(c, db)
+		if !ok {
+			return err
+		}
+		return createAPIKey(c, db, user.ID)
+	}
+}
+
+// apiKeyOwner loads the user named by the id path parameter. When ok is false
+// the error response has already been written and err must be returned.
+func apiKeyOwner(c echo.Context, db *gorm.DB) (*models.User, bool, error) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return nil, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid user ID",
+		})
+	}
+
+	user, err := repository.GetUserByID(db, userID)
+	if err != nil {
+		return nil, false, userLookupError(c, err)
+	}
+	return user, true, nil
+}
+
+// listAPIKeys writes the API keys of a user
+func listAPIKeys(c echo.Context, db *gorm.DB, userID uuid.UUID) error {
+	keys, err := repository.ListAPIKeys(db, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve API keys",
+		})
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// createAPIKey validates the request and creates an API key for a user
+func createAPIKey(c echo.Context, db *gorm.DB, userID uuid.UUID) error {
+	var req models.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to parse JSON",
+		})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Name is required and must be at most 100 characters",
+		})
+	}
+
+	if len(req.Scopes) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "At least one scope is required",
+		})
+	}
+	scopes := models.StringList{}
+	for _, scope := range req.Scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Unknown scope: " + scope,
+			})
+		}
+		if !scopes.Contains(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Expiry must be in the future",
+		})
+	}
+
+	plain, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate API key",
+		})
+	}
+
+	key := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   auth.HashToken(plain),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: middleware.CurrentUser(c).ID,
+	}
+	if err := repository.CreateAPIKey(requestDB(c, db), &key); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create API key",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.CreateAPIKeyResponse{
+		APIKey: key,
+		Key:    plain,
+	})
+}
@@ -0,0 +1,222 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/audit.go
+
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// requestDB binds the database handle to the request context so that changes
+// made through it are attributed to the caller in the audit log
+func requestDB(c echo.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c.Request().Context())
+}
+
+// ListAuditLogs godoc
+// @Summary List audit log
+// @Description Lists audit log entries, newest first (admin only)
+// @Tags Audit
+// @Security ApiKeyAuth
+// @Produce json
+// @Param user_id query string false "Actor user ID"
+// @Param action query string false "Action, e.g. permission.grant"
+// @Param entity_type query string false "Entity type, e.g. user"
+// @Param entity_id query string false "Entity ID"
+// @Param from query string false "Start of time range (RFC 3339, inclusive)"
+// @Param to query string false "End of time range (RFC 3339, exclusive)"
+// @Param page query int false "Page number (default 1)"
+// @Param per_page query int false "Entries per page (default 50, max 200)"
+// @Success 200 {object} models.Page[models.AuditLog]
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 500 {object} map[string]string
+// @Router /api/audit [get]
+func ListAuditLogs(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		filter, err := parseAuditFilter(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		page, perPage, err := parsePagination(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		entries, total, err := repository.ListAuditLogs(db, filter, (page-1)*perPage, perPage)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve audit log",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.Page[models.AuditLog]{
+			Items:   entries,
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+		})
+	}
+}
+
+// ExportAuditLogs godoc
+// @Summary Export audit log
+// @Description Streams all matching audit log entries, oldest first, as CSV or NDJSON (admin only)
+// @Tags Audit
+// @Security ApiKeyAuth
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or ndjson"
+// @Param user_id query string false "Actor user ID"
+// @Param action query string false "Action, e.g. permission.grant"
+// @Param entity_type query string false "Entity type, e.g. user"
+// @Param entity_id query string false "Entity ID"
+// @Param from query string false "Start of time range (RFC 3339, inclusive)"
+// @Param to query string false "End of time range (RFC 3339, exclusive)"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /api/audit/export [get]
+func ExportAuditLogs(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		filter, err := parseAuditFilter(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		format := c.QueryParam("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "ndjson" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "format must be csv or ndjson",
+			})
+		}
+
+		res := c.Response()
+		filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+		res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+		// Once streaming has started errors can only be logged, not reported
+		if format == "ndjson" {
+			res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+			res.WriteHeader(http.StatusOK)
+
+			encoder := json.NewEncoder(res)
+			return repository.EachAuditLog(db, filter, func(entry *models.AuditLog) error {
+				return encoder.Encode(entry)
+			})
+		}
+
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		res.WriteHeader(http.StatusOK)
+
+		writer := csv.NewWriter(res)
+		if err := writer.Write([]string{"id", "created_at", "user_id", "action", "entity_type", "entity_id", "changes", "ip_address", "user_agent"}); err != nil {
+			return err
+		}
+		err = repository.EachAuditLog(db, filter, func(entry *models.AuditLog) error {
+			return writer.Write([]string{
+				entry.ID.String(),
+				entry.CreatedAt.UTC().Format(time.RFC3339),
+				optionalID(entry.UserID),
+				entry.Action,
+				entry.EntityType,
+				optionalID(entry.EntityID),
+				string(entry.Changes),
+				entry.IPAddress,
+				entry.UserAgent,
+			})
+		})
+		writer.Flush()
+		if err != nil {
+			return err
+		}
+		return writer.Error()
+	}
+}
+
+// parseAuditFilter reads the audit log filters from the query string
+func parseAuditFilter(c echo.Context) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		Action:     c.QueryParam("action"),
+		EntityType: c.QueryParam("entity_type"),
+	}
+
+	if value := c.QueryParam("user_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = &id
+	}
+
+	if value := c.QueryParam("entity_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, errors.New("invalid entity_id")
+		}
+		filter.EntityID = &id
+	}
+
+	if value := c.QueryParam("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New("from must be an RFC 3339 timestamp")
+		}
+		filter.From = &from
+	}
+
+	if value := c.QueryParam("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New("to must be an RFC 3339 timestamp")
+		}
+		filter.To = &to
+	}
+
+	return filter, nil
+}
+
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,335 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/auth.go
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Login godoc
+// @Summary Log in
+// @Description Verifies credentials and creates a new server-side session. Web clients receive an httpOnly session cookie and a CSRF token instead of the token. Accounts with two-factor authentication, and admins when it is required for them, instead get a models.TwoFactorChallengeResponse to complete at /api/auth/login/2fa.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Login credentials"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid username or password"
+// @Failure 429 {object} map[string]interface{} "Too many failed attempts for the account or IP address"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/login [post]
+func Login(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var loginReq models.LoginRequest
+
+		// Parse JSON body into LoginRequest struct
+		if err := c.Bind(&loginReq); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if loginReq.Username == "" || loginReq.Password == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Username and password are required",
+			})
+		}
+
+		// Clients that don't say otherwise are treated as mobile clients
+		if loginReq.ClientType == "" {
+			loginReq.ClientType = models.ClientTypeMobile
+		}
+		if !models.IsValidClientType(loginReq.ClientType) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid client type",
+			})
+		}
+
+		// Guessing is throttled per account and per client IP address
+		policy, err := auth.LoadLockoutPolicy(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify credentials",
+			})
+		}
+		if ok, err := checkLoginThrottle(c, db, policy,
+			auth.AccountThrottleKey(loginReq.Username), auth.IPThrottleKey(c.RealIP())); !ok {
+			return err
+		}
+
+		user, err := repository.GetUserByUsername(db, loginReq.Username)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify credentials",
+			})
+		}
+		if user == nil {
+			auth.EqualizeTiming(loginReq.Password)
+			recordLoginFailure(c, db, policy, loginReq.Username, nil)
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Unauthorized - Invalid username or password",
+			})
+		}
+
+		ok, needsRehash, err := auth.VerifyPassword(user.PasswordHash, loginReq.Password)
+		if err != nil {
+			log.Printf("Error verifying password for user %s: %v", user.ID, err)
+		}
+		if !ok {
+			recordLoginFailure(c, db, policy, loginReq.Username, &user.ID)
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Unauthorized - Invalid username or password",
+			})
+		}
+
+		// Only tell the account is deactivated once the password is proven
+		if !user.IsActive {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Account is deactivated",
+			})
+		}
+
+		// Temporary passwords of invited users expire with their invitation
+		if user.FirstLogin {
+			invitation, err := repository.GetLatestInvitation(db, user.ID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to verify credentials",
+				})
+			}
+			if invitation != nil && !invitation.IsPending(time.Now()) {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Forbidden - Invitation has expired",
+				})
+			}
+		}
+
+		// Upgrade the stored hash if the hashing parameters have changed
+		if needsRehash {
+			if newHash, err := auth.HashPassword(loginReq.Password); err != nil {
+				log.Printf("Error rehashing password for user %s: %v", user.ID, err)
+			} else if err := repository.UpdateUserPasswordHash(db, user.ID, newHash); err != nil {
+				log.Printf("Error storing rehashed password for user %s: %v", user.ID, err)
+			}
+		}
+
+		// Accounts with two-factor authentication only get a session after the
+		// second step; admins may have to enroll first
+		required, err := twoFactorRequired(db, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify credentials",
+			})
+		}
+		if user.TwoFactorEnabled || required {
+			return startLoginChallenge(c, db, user, loginReq.ClientType)
+		}
+
+		return startSession(c, db, user, loginReq.ClientType, nil)
+	}
+}
+
+// startSession creates a session for a user whose credentials have been
+// verified and writes the login response. Recovery codes are included when
+// two-factor authentication was enrolled during the login.
+func startSession(c echo.Context, db *gorm.DB, user *models.User, clientType string, recoveryCodes []string) error {
+	response, err := issueSession(c, db, user, clientType)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create session",
+		})
+	}
+
+	clearLoginFailures(db, user)
+
+	response.RecoveryCodes = recoveryCodes
+	return c.JSON(http.StatusOK, response)
+}
+
+// issueSession creates a session for a fully authenticated user. Web sessions
+// get their cookies set on the response; other clients find the token in the
+// returned login response.
+func issueSession(c echo.Context, db *gorm.DB, user *models.User, clientType string) (*models.LoginResponse, error) {
+	maxDuration, activityExtension, err := auth.SessionLifetime(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is only handed out once; the database keeps its hash
+	token, err := models.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		TokenHash:    auth.HashToken(token),
+		UserID:       user.ID,
+		CreatedAt:    now,
+		LastActivity: now,
+		IPAddress:    c.RealIP(),
+		UserAgent:    c.Request().UserAgent(),
+		ClientType:   clientType,
+		IsActive:     true,
+	}
+
+	// Web sessions are carried in a cookie and need a CSRF token
+	if session.ClientType == models.ClientTypeWeb {
+		csrfToken, err := models.GenerateToken(32)
+		if err != nil {
+			return nil, err
+		}
+		session.CSRFToken = csrfToken
+	}
+
+	if err := repository.CreateSession(db, &session); err != nil {
+		return nil, err
+	}
+
+	response := &models.LoginResponse{
+		ExpiresAt:  session.ExpiresAt(maxDuration, activityExtension),
+		FirstLogin: user.FirstLogin,
+		User:       *user,
+	}
+
+	// The web client never sees the token; it only lives in an httpOnly cookie
+	if session.ClientType == models.ClientTypeWeb {
+		auth.SetSessionCookies(c, token, session.CSRFToken, session.CreatedAt.Add(maxDuration))
+		response.CSRFToken = session.CSRFToken
+	} else {
+		response.Token = token
+	}
+
+	return response, nil
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Terminates the session used to authenticate the request
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/logout [post]
+func Logout(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session := middleware.CurrentSession(c)
+
+		if err := repository.DeactivateSession(db, session.TokenHash); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to terminate session",
+			})
+		}
+
+		if session.ClientType == models.ClientTypeWeb {
+			auth.ClearSessionCookies(c)
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Logged out successfully",
+		})
+	}
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Changes the password of the authenticated user after verifying the current one
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param passwords body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Current password is incorrect"
+// @Failure 429 {object} map[string]interface{} "Too many failed attempts"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/password [post]
+func ChangePassword(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.ChangePasswordRequest
+
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+
+		policy, err := auth.LoadLockoutPolicy(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify password",
+			})
+		}
+		if ok, err := checkLoginThrottle(c, db, policy,
+			auth.AccountThrottleKey(user.Username), auth.IPThrottleKey(c.RealIP())); !ok {
+			return err
+		}
+
+		ok, _, err := auth.VerifyPassword(user.PasswordHash, req.CurrentPassword)
+		if err != nil {
+			log.Printf("Error verifying password for user %s: %v", user.ID, err)
+		}
+		if !ok {
+			recordLoginFailure(c, db, policy, user.Username, &user.ID)
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Current password is incorrect",
+			})
+		}
+
+		if err := auth.ValidatePassword(req.NewPassword); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		hash, err := auth.HashPassword(req.NewPassword)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to hash password",
+			})
+		}
+
+		if err := repository.ChangeUserPassword(requestDB(c, db), user.ID, hash); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update password",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Password changed successfully",
+		})
+	}
+}
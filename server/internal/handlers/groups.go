@@ -0,0 +1,438 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/groups.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/settings"
+	"gorm.io/gorm"
+)
+
+// maxGroupNameLength matches the size of the name column
+const maxGroupNameLength = 255
+
+// ListGroups godoc
+// @Summary List groups
+// @Description Lists groups ordered by name. Requires can_manage_groups.
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Produce json
+// @Param search query string false "Matches the group name"
+// @Param deleted query bool false "List soft-deleted groups instead (admin only)"
+// @Param page query int false "Page number (default 1)"
+// @Param per_page query int false "Groups per page (default 50, max 200)"
+// @Success 200 {object} models.Page[models.Group]
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 500 {object} map[string]string
+// @Router /api/groups [get]
+func ListGroups(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		page, perPage, err := parsePagination(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		var deleted bool
+		if value := c.QueryParam("deleted"); value != "" {
+			if deleted, err = strconv.ParseBool(value); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid deleted",
+				})
+			}
+		}
+		if deleted && !middleware.CurrentUser(c).IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Admin role required",
+			})
+		}
+
+		search := strings.TrimSpace(c.QueryParam("search"))
+		groups, total, err := repository.ListGroups(db, search, deleted, (page-1)*perPage, perPage)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve groups",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.Page[models.Group]{
+			Items:   groups,
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+		})
+	}
+}
+
+// GetGroup godoc
+// @Summary Get a group
+// @Description Returns a single group. Requires can_manage_groups.
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id} [get]
+func GetGroup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		group, err := repository.GetGroupByID(db, groupID)
+		if err != nil {
+			return groupLookupError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, group)
+	}
+}
+
+// CreateGroup godoc
+// @Summary Create a group
+// @Description Creates a group. Names are unique regardless of case. Requires can_manage_groups.
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param group body models.GroupRequest true "Group name"
+// @Success 201 {object} models.Group
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 409 {object} map[string]string "Group name is already taken"
+// @Failure 500 {object} map[string]string
+// @Router /api/groups [post]
+func CreateGroup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name, ok, err := bindGroupName(c)
+		if !ok {
+			return err
+		}
+
+		group := models.Group{Name: name}
+		if err := repository.CreateGroup(requestDB(c, db), &group); err != nil {
+			if errors.Is(err, repository.ErrGroupNameTaken) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Group name is already taken",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to create group",
+			})
+		}
+
+		return c.JSON(http.StatusCreated, group)
+	}
+}
+
+// RenameGroup godoc
+// @Summary Rename a group
+// @Description Changes the name of a group. Requires can_manage_groups.
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param group body models.GroupRequest true "New group name"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Group name is already taken"
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id} [patch]
+func RenameGroup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		name, ok, err := bindGroupName(c)
+		if !ok {
+			return err
+		}
+
+		group, err := repository.RenameGroup(requestDB(c, db), groupID, name)
+		if err != nil {
+			if errors.Is(err, repository.ErrGroupNameTaken) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Group name is already taken",
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return groupLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to rename group",
+			})
+		}
+
+		return c.JSON(http.StatusOK, group)
+	}
+}
+
+// DeleteGroup godoc
+// @Summary Delete a group
+// @Description Soft-deletes an empty group; admins can restore it until it is purged. The default "Main" group can never be deleted. Requires can_manage_groups.
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Default group or group still has users"
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id} [delete]
+func DeleteGroup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		if err := repository.DeleteGroup(requestDB(c, db), groupID); err != nil {
+			switch {
+			case errors.Is(err, repository.ErrDefaultGroup):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "The default group cannot be deleted",
+				})
+			case errors.Is(err, repository.ErrGroupNotEmpty):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "The group still has users; move them first",
+				})
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return groupLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to delete group",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Group deleted successfully",
+		})
+	}
+}
+
+// RestoreGroup godoc
+// @Summary Restore a deleted group
+// @Description Brings back a soft-deleted group (admin only)
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Group name is already taken"
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id}/restore [post]
+func RestoreGroup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		group, err := repository.RestoreGroup(requestDB(c, db), groupID)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrGroupNameTaken):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Group name is already taken; rename the other group first",
+				})
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Deleted group not found",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to restore group",
+			})
+		}
+
+		return c.JSON(http.StatusOK, group)
+	}
+}
+
+// MoveUsersToGroup godoc
+// @Summary Move users into a group
+// @Description Moves several users into a group at once; users already in it are left alone. Requires can_manage_groups, and can_manage_users for every moved user and for the members of the target group. Only admins can move admins.
+// @Tags Groups
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Target group ID"
+// @Param request body models.MoveUsersRequest true "Users to move"
+// @Success 200 {object} models.MoveUsersResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission or moving an admin"
+// @Failure 404 {object} map[string]string "Group or one of the users not found"
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id}/members [post]
+func MoveUsersToGroup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		var req models.MoveUsersRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if len(req.UserIDs) == 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "At least one user ID is required",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+		moved, err := repository.MoveUsers(requestDB(c, db), groupID, uniqueIDs(req.UserIDs),
+			func(tx *gorm.DB, users []models.User) error {
+				return authorizeMove(tx, user, groupID, users)
+			})
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Group or user not found",
+				})
+			case errors.Is(err, errMoveAdmin):
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Forbidden - Only admins can move admins",
+				})
+			case errors.Is(err, errMoveNotPermitted):
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error":      "Forbidden - Missing permission",
+					"permission": models.PermissionManageUsers,
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to move users",
+			})
+		}
+
+		// Moved users now resolve their settings through another group
+		if moved > 0 {
+			settings.NotifyChanged()
+		}
+
+		return c.JSON(http.StatusOK, models.MoveUsersResponse{
+			Moved: moved,
+		})
+	}
+}
+
+// Reasons authorizeMove refuses to move users
+var (
+	errMoveAdmin        = errors.New("only admins can move admins")
+	errMoveNotPermitted = errors.New("missing can_manage_users")
+)
+
+// authorizeMove checks that user may manage every moved user as well as the
+// members of the target group, just like when changing a user's group one by one
+func authorizeMove(tx *gorm.DB, user *models.User, groupID uuid.UUID, users []models.User) error {
+	if user.IsAdmin() {
+		return nil
+	}
+
+	// A user that does not exist yet stands in for any member of the group
+	targets := append([]models.User{{GroupID: groupID}}, users...)
+	for i := range targets {
+		if targets[i].IsAdmin() {
+			return errMoveAdmin
+		}
+		allowed, err := auth.HasPermission(tx, user, models.PermissionManageUsers, &targets[i])
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errMoveNotPermitted
+		}
+	}
+	return nil
+}
+
+// bindGroupName reads and checks the group name from the request body. When
+// ok is false the error response has already been written and err must be
+// returned.
+func bindGroupName(c echo.Context) (string, bool, error) {
+	var req models.GroupRequest
+	if err := c.Bind(&req); err != nil {
+		return "", false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to parse JSON",
+		})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Name is required",
+		})
+	}
+	if len(name) > maxGroupNameLength {
+		return "", false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Name is too long",
+		})
+	}
+
+	return name, true, nil
+}
@@ -0,0 +1,212 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/invitations.go
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/config"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/mailer"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ResendInvitation godoc
+// @Summary Resend an invitation
+// @Description Issues a new temporary password and invitation link for a user who has not logged in yet; earlier invitations stop working. Requires can_manage_users for the user; only admins can invite admins.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.InvitationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "User has already logged in"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/invite [post]
+func ResendInvitation(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+		target := middleware.TargetUser(c)
+
+		if target.IsAdmin() && !user.IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Only admins can invite admins",
+			})
+		}
+
+		if !target.FirstLogin {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "User has already set their own password",
+			})
+		}
+
+		password, err := auth.GenerateTemporaryPassword()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate password",
+			})
+		}
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to hash password",
+			})
+		}
+
+		invitation, err := inviteUser(c, db, target, password, hash)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to create invitation",
+			})
+		}
+
+		return c.JSON(http.StatusOK, invitation)
+	}
+}
+
+// AcceptInvite godoc
+// @Summary Accept an invitation
+// @Description Sets the password of an invited user with the token from the invitation email. Afterwards the user logs in normally.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptInviteRequest true "Invitation token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "Invalid or expired invitation, or password does not meet the policy"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/accept-invite [post]
+func AcceptInvite(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.AcceptInviteRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if req.Token == "" || req.Password == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Token and password are required",
+			})
+		}
+
+		if err := auth.ValidatePassword(req.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to hash password",
+			})
+		}
+
+		if _, err := repository.AcceptInvitation(requestDB(c, db), auth.HashToken(req.Token), hash); err != nil {
+			if errors.Is(err, repository.ErrInvitationInvalid) {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invitation is invalid or has expired",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to accept invitation",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Invitation accepted, you can now log in",
+		})
+	}
+}
+
+// inviteData is what the invitation templates are rendered with
+type inviteData struct {
+	FullName          string
+	Username          string
+	TemporaryPassword string
+	InvitedBy         string
+	AcceptURL         string
+	ExpiresAt         time.Time
+}
+
+// inviteUser stores a new invitation for a user and emails it together with
+// the temporary password. A non-empty passwordHash replaces the user's
+// current password. Delivery problems are logged and reported in the
+// response rather than failing the request, since the invitation can be
+// resent. The temporary password is only in the email, so an unsent
+// invitation is never reported as sent.
+func inviteUser(c echo.Context, db *gorm.DB, user *models.User, password, passwordHash string) (*models.InvitationResponse, error) {
+	token, err := models.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	inviter := middleware.CurrentUser(c)
+	invitation := models.Invitation{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(config.AppConfig.InviteExpiryHours) * time.Hour),
+		CreatedBy: inviter.ID,
+	}
+	if err := repository.CreateInvitation(requestDB(c, db), &invitation, passwordHash); err != nil {
+		return nil, err
+	}
+
+	invitedBy := inviter.FullName
+	if invitedBy == "" {
+		invitedBy = inviter.Username
+	}
+
+	response := &models.InvitationResponse{InvitationExpiresAt: invitation.ExpiresAt}
+	msg, err := mailer.Render(user.Email, "You have been invited to Life Beacon 360", mailer.TemplateInvite, inviteData{
+		FullName:          user.FullName,
+		Username:          user.Username,
+		TemporaryPassword: password,
+		InvitedBy:         invitedBy,
+		AcceptURL:         config.AppConfig.AppBaseURL + "/accept-invite?token=" + url.QueryEscape(token),
+		ExpiresAt:         invitation.ExpiresAt,
+	})
+	if err == nil {
+		err = mailer.Send(msg)
+	}
+	if errors.Is(err, mailer.ErrNotConfigured) {
+		response.InvitationError = "Email is not configured; resend the invitation once SMTP_HOST is set"
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error sending invitation to user %s: %v", user.ID, err)
+		response.InvitationError = "Failed to send the invitation email; resend the invitation"
+		return response, nil
+	}
+
+	response.InvitationSent = true
+	return response, nil
+}
@@ -21,52 +21,74 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/validation"
 	"gorm.io/gorm"
 )
 
 // CreateLocation godoc
 // @Summary Save location
-// @Description Stores location coordinates in the database
+// @Description Stores a location fix with optional accuracy, altitude, speed, bearing, battery level and device time (recorded_at, defaults to the receive time). Replays with the same Idempotency-Key, or of a point with the same client_id and recorded_at or client_id and sequence, are answered with success and the Idempotent-Replayed header without storing the point again.
 // @Tags Location
 // @Security ApiKeyAuth  // This tells Swagger that this endpoint needs the token
 // @Accept json
 // @Produce json
 // @Param location body models.LocationRequest true "Location data"
+// @Param Idempotency-Key header string false "Unique key of this upload, at most 255 characters"
 // @Success 201 {object} map[string]string
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} models.ValidationErrorResponse
 // @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - API key lacks the required scope"
 // @Failure 500 {object} map[string]string
 // @Router /api/locations [post]
 func CreateLocation(db *gorm.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var locationReq models.LocationRequest
 
-		fmt.Println("Received request to /api/locations")
-		fmt.Printf("Headers: %v\n", c.Request().Header)
-
 		// Parse JSON body into LocationRequest struct
 		if err := c.Bind(&locationReq); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Failed to parse JSON",
+			return invalidLocation(c, "Failed to parse JSON", validation.BindError(err))
+		}
+
+		now := time.Now()
+		if fieldErrors := validation.Location(&locationReq, now); fieldErrors != nil {
+			return invalidLocation(c, "Invalid location", fieldErrors)
+		}
+
+		// Retries with the same Idempotency-Key do not store the location again
+		key := strings.TrimSpace(c.Request().Header.Get(idempotencyKeyHeader))
+		if len(key) > maxIdempotencyKeyLength {
+			return invalidLocation(c, "Invalid location", models.FieldErrors{
+				idempotencyKeyHeader: {
+					Code:    validation.CodeTooLong,
+					Message: fmt.Sprintf("must be at most %d characters", maxIdempotencyKeyLength),
+				},
 			})
 		}
 
-		// Map to Location model
-		location := models.Location{
-			Latitude:  locationReq.Latitude,
-			Longitude: locationReq.Longitude,
+		location := newLocation(c, &locationReq, now)
+		if key != "" {
+			location.IdempotencyKey = &key
 		}
 
 		// Save the location using repository function
-		if err := repository.SaveCoordinate(db, &location); err != nil {
+		created, err := repository.SaveCoordinate(db, &location)
+		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{
 				"error": "Failed to save location",
 			})
 		}
+		if !created {
+			c.Response().Header().Set(idempotentReplayedHeader, "true")
+		}
 
 		return c.JSON(http.StatusCreated, map[string]string{
 			"message": "Location saved successfully",
@@ -74,21 +96,96 @@ func CreateLocation(db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
+// CreateLocationBatch godoc
+// @Summary Save a batch of locations
+// @Description Stores up to 500 queued location fixes in a single transaction. Each point gets a result: "created" and "duplicate" (stored by an earlier upload, matched by client_id and recorded_at or client_id and sequence) points can be dropped from the client's queue, "rejected" points are invalid and must not be retried. If the batch cannot be stored as a whole, nothing is stored and the request should be retried.
+// @Tags Location
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param batch body models.LocationBatchRequest true "Locations"
+// @Success 200 {object} models.LocationBatchResponse
+// @Failure 400 {object} models.ValidationErrorResponse
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - API key lacks the required scope"
+// @Failure 500 {object} map[string]string
+// @Router /api/locations/batch [post]
+func CreateLocationBatch(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var batchReq models.LocationBatchRequest
+		if err := c.Bind(&batchReq); err != nil {
+			return invalidLocation(c, "Failed to parse JSON", validation.BindError(err))
+		}
+
+		if fieldErrors := validation.Batch(&batchReq); fieldErrors != nil {
+			return invalidLocation(c, "Invalid batch", fieldErrors)
+		}
+
+		// Invalid points are reported individually, the rest is stored together
+		now := time.Now()
+		results := make([]models.LocationBatchResult, len(batchReq.Locations))
+		locations := make([]models.Location, 0, len(batchReq.Locations))
+		indexes := make([]int, 0, len(batchReq.Locations))
+		for i := range batchReq.Locations {
+			results[i].Index = i
+			if fieldErrors := validation.Location(&batchReq.Locations[i], now); fieldErrors != nil {
+				results[i].Status = models.LocationResultRejected
+				results[i].Error = "Invalid location"
+				results[i].Fields = fieldErrors
+				continue
+			}
+			locations = append(locations, newLocation(c, &batchReq.Locations[i], now))
+			indexes = append(indexes, i)
+		}
+
+		duplicates, err := repository.SaveLocations(db, locations)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to save locations",
+			})
+		}
+
+		for i, location := range locations {
+			result := &results[indexes[i]]
+			if id, ok := duplicates[i]; ok {
+				result.Status = models.LocationResultDuplicate
+				result.ID = id
+			} else {
+				result.Status = models.LocationResultCreated
+				result.ID = location.ID
+			}
+		}
+
+		return c.JSON(http.StatusOK, models.LocationBatchResponse{
+			Created:   len(locations) - len(duplicates),
+			Duplicate: len(duplicates),
+			Rejected:  len(results) - len(locations),
+			Results:   results,
+		})
+	}
+}
+
 // GetLatestLocations godoc
 // @Summary Get latest locations
-// @Description Retrieves the latest 10 location coordinates from the database
+// @Description Retrieves the 10 most recently recorded locations of the users the caller holds can_view_location for. API keys with locations:read:self only get their own user's locations.
 // @Tags Location
 // @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Success 200 {array} models.Location
 // @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - API key lacks the required scope or its user may not view their own locations"
 // @Failure 500 {object} map[string]string
 // @Router /api/locations [get]
 func GetLatestLocations(db *gorm.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		// Get the latest 10 locations
-		locations, err := repository.GetLatestLocations(db, 10)
+		scope, ok, err := locationScope(c, db)
+		if !ok {
+			return err
+		}
+
+		// Get the latest 10 locations the caller may see
+		locations, err := repository.GetLatestLocations(db, scope, 10)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{
 				"error": "Failed to retrieve locations: " + err.Error(),
@@ -99,3 +196,86 @@ func GetLatestLocations(db *gorm.DB) echo.HandlerFunc {
 		return c.JSON(http.StatusOK, locations)
 	}
 }
+
+const (
+	// idempotencyKeyHeader lets clients retry a single upload safely; replays
+	// are answered like the original request plus idempotentReplayedHeader
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// newLocation maps a validated location request to a location owned by the
+// user behind the session or API key
+func newLocation(c echo.Context, req *models.LocationRequest, receivedAt time.Time) models.Location {
+	// Sessions know their client type, so it only has to be sent with API keys
+	clientType := req.ClientType
+	if session := middleware.CurrentSession(c); clientType == "" && session != nil {
+		clientType = session.ClientType
+	}
+
+	// Points queued on the device keep the time they were recorded. Times are
+	// stored with microsecond precision, so they are cut off at that to match
+	// stored points when deduplicating.
+	receivedAt = receivedAt.UTC().Truncate(time.Microsecond)
+	recordedAt := receivedAt
+	if req.RecordedAt != nil {
+		recordedAt = req.RecordedAt.UTC().Truncate(time.Microsecond)
+	}
+
+	return models.Location{
+		UserID:       middleware.CurrentUser(c).ID,
+		Latitude:     *req.Latitude,
+		Longitude:    *req.Longitude,
+		Accuracy:     req.Accuracy,
+		Altitude:     req.Altitude,
+		Speed:        req.Speed,
+		Bearing:      req.Bearing,
+		BatteryLevel: req.BatteryLevel,
+		RecordedAt:   recordedAt,
+		ReceivedAt:   receivedAt,
+		ClientID:     req.ClientID,
+		ClientType:   clientType,
+		Sequence:     req.Sequence,
+	}
+}
+
+// invalidLocation writes the response for a location upload with invalid fields
+func invalidLocation(c echo.Context, message string, fieldErrors models.FieldErrors) error {
+	return c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+		Error:  message,
+		Fields: fieldErrors,
+	})
+}
+
+// locationScope resolves whose locations the caller may see: the users their
+// can_view_location grants cover, narrowed to the key's own user for API
+// keys. A key whose user may not view their own locations gets a 403 rather
+// than an empty list. When ok is false the error response has already been
+// written and err must be returned.
+func locationScope(c echo.Context, db *gorm.DB) (models.PermissionScope, bool, error) {
+	user := middleware.CurrentUser(c)
+
+	if middleware.CurrentAPIKey(c) != nil {
+		allowed, err := auth.HasPermission(db, user, models.PermissionViewLocation, user)
+		if err != nil {
+			return models.PermissionScope{}, false, c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to check permissions",
+			})
+		}
+		if !allowed {
+			return models.PermissionScope{}, false, c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - API key owner may not view locations",
+			})
+		}
+		return models.PermissionScope{UserIDs: []uuid.UUID{user.ID}}, true, nil
+	}
+
+	scope, err := auth.ResolveScope(db, user, models.PermissionViewLocation)
+	if err != nil {
+		return models.PermissionScope{}, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to check permissions",
+		})
+	}
+	return scope, true, nil
+}
@@ -0,0 +1,203 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/lockouts.go
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ListLockouts godoc
+// @Summary List login lockouts
+// @Description Lists accounts and IP addresses that are locked out or have recent failed logins (admin only). Account keys hold the lowercased username, whether or not such a user exists.
+// @Tags Lockouts
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} models.LoginThrottle
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 500 {object} map[string]string
+// @Router /api/lockouts [get]
+func ListLockouts(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		policy, err := auth.LoadLockoutPolicy(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve lockouts",
+			})
+		}
+
+		throttles, err := repository.ListLoginThrottles(db, policy.Duration)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve lockouts",
+			})
+		}
+
+		return c.JSON(http.StatusOK, throttles)
+	}
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user
+// @Description Lifts the login lockout of a user and forgets their failed logins (admin only)
+// @Tags Lockouts
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found or not locked out"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/unlock [post]
+func UnlockUser(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		user, err := repository.GetUserByID(db, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "User not found",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve user",
+			})
+		}
+
+		return unlock(c, db, auth.AccountThrottleKey(user.Username), &user.ID)
+	}
+}
+
+// UnlockIP godoc
+// @Summary Unlock an IP address
+// @Description Lifts the login lockout of an IP address and forgets its failed logins (admin only)
+// @Tags Lockouts
+// @Security ApiKeyAuth
+// @Produce json
+// @Param ip path string true "IP address"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "IP address not locked out"
+// @Failure 500 {object} map[string]string
+// @Router /api/lockouts/ip/{ip} [delete]
+func UnlockIP(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return unlock(c, db, auth.IPThrottleKey(c.Param("ip")), nil)
+	}
+}
+
+// unlock removes a throttle and writes the response
+func unlock(c echo.Context, db *gorm.DB, key string, userID *uuid.UUID) error {
+	if err := repository.UnlockLoginThrottle(requestDB(c, db), key, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "No failed logins recorded",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to unlock",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Unlocked",
+	})
+}
+
+// checkLoginThrottle refuses an attempt to prove credentials while any of
+// the given throttle keys is locked out or backing off. When ok is false the
+// error response has already been written and err must be returned.
+func checkLoginThrottle(c echo.Context, db *gorm.DB, policy auth.LockoutPolicy, keys ...string) (bool, error) {
+	throttles, err := repository.GetLoginThrottles(db, keys...)
+	if err != nil {
+		return false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to verify credentials",
+		})
+	}
+
+	now := time.Now()
+	var retryAt time.Time
+	for i := range throttles {
+		if at, blocked := policy.LoginRetryAt(&throttles[i], now); blocked && at.After(retryAt) {
+			retryAt = at
+		}
+	}
+	if retryAt.IsZero() {
+		return true, nil
+	}
+
+	return false, tooManyAttempts(c, retryAt.Sub(now))
+}
+
+// tooManyAttempts writes the response for a throttled attempt
+func tooManyAttempts(c echo.Context, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+		"error":       "Too many failed attempts, please try again later",
+		"retry_after": seconds,
+	})
+}
+
+// recordLoginFailure counts a failed attempt against the account and the
+// client IP. Errors are only logged, the attempt has failed anyway.
+func recordLoginFailure(c echo.Context, db *gorm.DB, policy auth.LockoutPolicy, username string, userID *uuid.UUID) {
+	keys := []struct {
+		key    string
+		userID *uuid.UUID
+	}{
+		{auth.AccountThrottleKey(username), userID},
+		{auth.IPThrottleKey(c.RealIP()), nil},
+	}
+
+	for _, k := range keys {
+		if _, err := repository.RecordLoginFailure(requestDB(c, db), k.key, policy.Threshold(k.key), policy.Duration, k.userID); err != nil {
+			log.Printf("Error recording failed login for %s: %v", k.key, err)
+		}
+	}
+}
+
+// clearLoginFailures forgets the failed logins of an account once it has
+// fully logged in
+func clearLoginFailures(db *gorm.DB, user *models.User) {
+	if err := repository.ClearLoginFailures(db, auth.AccountThrottleKey(user.Username)); err != nil {
+		log.Printf("Error clearing failed logins of user %s: %v", user.ID, err)
+	}
+}
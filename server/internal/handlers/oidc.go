@@ -0,0 +1,329 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/oidc.go
+
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/config"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/oidc"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcLoginLifetime is how long a user may take at the identity provider
+	oidcLoginLifetime = 10 * time.Minute
+
+	// maxUsernameAttempts bounds the numbered usernames tried when provisioning
+	maxUsernameAttempts = 20
+)
+
+var (
+	errOIDCNoAccount        = errors.New("no account matches the identity")
+	errOIDCAccountDeleted   = errors.New("the linked account has been deleted")
+	errOIDCEmailNotVerified = errors.New("the email address is not verified")
+	errOIDCAmbiguousEmail   = errors.New("several accounts have the email address")
+)
+
+// usernameInvalidChars matches what is dropped from provisioned usernames
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// GetOIDCStatus godoc
+// @Summary Get single sign-on status
+// @Description Tells clients whether login with the company identity provider is available and where to start it
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} models.OIDCStatusResponse
+// @Router /api/auth/oidc [get]
+func GetOIDCStatus(provider *oidc.Provider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if provider == nil {
+			return c.JSON(http.StatusOK, models.OIDCStatusResponse{})
+		}
+		return c.JSON(http.StatusOK, models.OIDCStatusResponse{
+			Enabled:  true,
+			LoginURL: "/api/auth/oidc/login",
+		})
+	}
+}
+
+// OIDCLogin godoc
+// @Summary Start a single sign-on login
+// @Description Redirects the browser to the identity provider (authorization code flow with PKCE). Only web sessions can be created this way.
+// @Tags Auth
+// @Success 302 "Redirect to the identity provider"
+// @Router /api/auth/oidc/login [get]
+func OIDCLogin(db *gorm.DB, provider *oidc.Provider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state, err := models.GenerateToken(32)
+		if err != nil {
+			return oidcFailure(c, "Failed to start single sign-on", err)
+		}
+		nonce, err := models.GenerateToken(16)
+		if err != nil {
+			return oidcFailure(c, "Failed to start single sign-on", err)
+		}
+		verifier, err := oidc.NewCodeVerifier()
+		if err != nil {
+			return oidcFailure(c, "Failed to start single sign-on", err)
+		}
+
+		authURL, err := provider.AuthCodeURL(c.Request().Context(), state, nonce, verifier)
+		if err != nil {
+			return oidcFailure(c, "The identity provider is not available", err)
+		}
+
+		login := models.OIDCLoginState{
+			StateHash:    auth.HashToken(state),
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			ExpiresAt:    time.Now().Add(oidcLoginLifetime),
+		}
+		if err := repository.CreateOIDCLoginState(db, &login); err != nil {
+			return oidcFailure(c, "Failed to start single sign-on", err)
+		}
+
+		auth.SetOIDCStateCookie(c, state, login.ExpiresAt)
+		return c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// OIDCCallback godoc
+// @Summary Complete a single sign-on login
+// @Description Redirect target for the identity provider. Validates state, exchanges the code, verifies the ID token and its nonce, then maps the identity to a user (linking by verified email or subject, or provisioning into the configured group). Redirects to the web client with a session cookie, or to the login page with a two-factor challenge or an sso_error.
+// @Tags Auth
+// @Param code query string false "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 302 "Redirect to the web client"
+// @Router /api/auth/oidc/callback [get]
+func OIDCCallback(db *gorm.DB, provider *oidc.Provider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := c.QueryParam("state")
+		cookie, err := c.Cookie(auth.OIDCStateCookieName)
+		auth.ClearOIDCStateCookie(c)
+		if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+			return oidcFailure(c, "Single sign-on login has expired, please try again", nil)
+		}
+
+		login, err := repository.ConsumeOIDCLoginState(db, auth.HashToken(state))
+		if err != nil {
+			return oidcFailure(c, "Single sign-on login has expired, please try again", err)
+		}
+
+		if errorCode := c.QueryParam("error"); errorCode != "" {
+			return oidcFailure(c, "The identity provider did not sign you in",
+				fmt.Errorf("%s: %s", errorCode, c.QueryParam("error_description")))
+		}
+
+		ctx := c.Request().Context()
+		idToken, err := provider.Exchange(ctx, c.QueryParam("code"), login.CodeVerifier)
+		if err != nil {
+			return oidcFailure(c, "The identity provider did not sign you in", err)
+		}
+		claims, err := provider.VerifyIDToken(ctx, idToken, login.Nonce)
+		if err != nil {
+			return oidcFailure(c, "The identity provider did not sign you in", err)
+		}
+
+		user, err := resolveOIDCUser(c, db, claims)
+		if err != nil {
+			switch {
+			case errors.Is(err, errOIDCNoAccount):
+				return oidcFailure(c, "There is no account for you, ask an administrator to create one", err)
+			case errors.Is(err, errOIDCAccountDeleted):
+				return oidcFailure(c, "Your account has been deleted", err)
+			case errors.Is(err, errOIDCEmailNotVerified):
+				return oidcFailure(c, "Your identity provider has not verified your email address", err)
+			case errors.Is(err, errOIDCAmbiguousEmail), errors.Is(err, repository.ErrOIDCAlreadyLinked):
+				return oidcFailure(c, "Your account cannot be matched, ask an administrator for help", err)
+			}
+			return oidcFailure(c, "Failed to sign you in", err)
+		}
+
+		if !user.IsActive {
+			return oidcFailure(c, "Your account is deactivated", nil)
+		}
+
+		// The identity provider replaces the password, not the second factor
+		required, err := twoFactorRequired(db, user)
+		if err != nil {
+			return oidcFailure(c, "Failed to sign you in", err)
+		}
+		if user.TwoFactorEnabled || required {
+			challenge, err := createLoginChallenge(db, user, models.ClientTypeWeb)
+			if err != nil {
+				return oidcFailure(c, "Failed to sign you in", err)
+			}
+			fragment := url.Values{}
+			fragment.Set("challenge_token", challenge.ChallengeToken)
+			fragment.Set("setup_required", fmt.Sprint(challenge.SetupRequired))
+			return c.Redirect(http.StatusFound, config.AppConfig.AppBaseURL+"/login#"+fragment.Encode())
+		}
+
+		if _, err := issueSession(c, db, user, models.ClientTypeWeb); err != nil {
+			return oidcFailure(c, "Failed to sign you in", err)
+		}
+
+		return c.Redirect(http.StatusFound, config.AppConfig.AppBaseURL+"/")
+	}
+}
+
+// oidcFailure sends the browser back to the login page with a message to
+// show. The underlying error, if any, is only logged.
+func oidcFailure(c echo.Context, message string, err error) error {
+	if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+	}
+	return c.Redirect(http.StatusFound, config.AppConfig.AppBaseURL+"/login?sso_error="+url.QueryEscape(message))
+}
+
+// resolveOIDCUser finds the user for a verified identity: the user already
+// linked to its subject, otherwise (when matching by email) the only user
+// with its verified email address, which then gets linked. Unknown
+// identities are provisioned if enabled.
+func resolveOIDCUser(c echo.Context, db *gorm.DB, claims *oidc.Claims) (*models.User, error) {
+	user, err := repository.GetUserByOIDCSubject(db, claims.Subject)
+	if err == nil {
+		if user.DeletedAt.Valid {
+			return nil, errOIDCAccountDeleted
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if config.AppConfig.OIDCMatchClaim == "email" {
+		if claims.Email == "" || !claims.EmailVerified {
+			return nil, errOIDCEmailNotVerified
+		}
+
+		users, err := repository.GetUsersByEmail(db, claims.Email)
+		if err != nil {
+			return nil, err
+		}
+		switch len(users) {
+		case 0:
+		case 1:
+			if err := repository.LinkOIDCSubject(requestDB(c, db), users[0].ID, claims.Subject); err != nil {
+				return nil, err
+			}
+			users[0].OIDCSubject = &claims.Subject
+			return &users[0], nil
+		default:
+			return nil, errOIDCAmbiguousEmail
+		}
+	}
+
+	if !config.AppConfig.OIDCAutoProvision {
+		return nil, errOIDCNoAccount
+	}
+	return provisionOIDCUser(c, db, claims)
+}
+
+// provisionOIDCUser creates a user for an identity without account in the
+// configured group. The random password is never shown; the user signs in
+// through the identity provider.
+func provisionOIDCUser(c echo.Context, db *gorm.DB, claims *oidc.Claims) (*models.User, error) {
+	var group *models.Group
+	var err error
+	if name := config.AppConfig.OIDCDefaultGroup; name != "" {
+		group, err = repository.GetGroupByName(db, name)
+	} else {
+		group, err = repository.GetDefaultGroup(db)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding group for new users: %w", err)
+	}
+
+	password, err := models.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	email := ""
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+
+	base := provisionedUsername(claims)
+	for i := 1; i <= maxUsernameAttempts; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s%d", base, i)
+		}
+
+		user := models.User{
+			GroupID:      group.ID,
+			Username:     username,
+			Email:        email,
+			PasswordHash: hash,
+			Role:         models.RoleUser,
+			FullName:     claims.Name,
+			IsActive:     true,
+			OIDCSubject:  &claims.Subject,
+		}
+		err := repository.CreateUser(requestDB(c, db), &user)
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	return nil, fmt.Errorf("no free username for %q", base)
+}
+
+// provisionedUsername derives a username from the preferred username or the
+// email address of an identity
+func provisionedUsername(claims *oidc.Claims) string {
+	candidates := []string{claims.PreferredUsername}
+	if at := strings.Index(claims.Email, "@"); at > 0 {
+		candidates = append(candidates, claims.Email[:at])
+	}
+
+	for _, candidate := range candidates {
+		username := usernameInvalidChars.ReplaceAllString(strings.ToLower(candidate), "")
+		if len(username) > 90 {
+			username = username[:90]
+		}
+		if username != "" {
+			return username
+		}
+	}
+	return "user"
+}
@@ -0,0 +1,202 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/password_reset.go
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/config"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/mailer"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// passwordResetRequested is the answer to every accepted reset request, so
+// it does not reveal whether the address belongs to an account
+const passwordResetRequested = "If the address belongs to an account, a password reset link has been sent to it"
+
+// RequestPasswordReset godoc
+// @Summary Request a password reset
+// @Description Emails a single-use password reset link to every active account with the given address. The response is the same whether or not the address is known. Each address may only make a limited number of requests per hour.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RequestPasswordResetRequest true "Email address of the account"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string "Too many requests for this address"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/password-reset/request [post]
+func RequestPasswordReset(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.RequestPasswordResetRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if req.Email == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Email is required",
+			})
+		}
+
+		since := time.Now().Add(-time.Hour)
+		if err := repository.RecordPasswordResetRequest(db, req.Email, c.RealIP(), since,
+			config.AppConfig.PasswordResetMaxPerHour); err != nil {
+			if errors.Is(err, repository.ErrTooManyResetRequests) {
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error": "Too many password reset requests, please try again later",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to request password reset",
+			})
+		}
+
+		users, err := repository.GetActiveUsersByEmail(db, req.Email)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to request password reset",
+			})
+		}
+
+		for i := range users {
+			if err := sendPasswordReset(db, &users[i]); err != nil {
+				log.Printf("Error creating password reset for user %s: %v", users[i].ID, err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to request password reset",
+				})
+			}
+		}
+
+		return c.JSON(http.StatusAccepted, map[string]string{
+			"message": passwordResetRequested,
+		})
+	}
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Reset a password
+// @Description Sets a new password with the token from a password reset email. The token can only be used once, and all sessions of the user are revoked afterwards.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ConfirmPasswordResetRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "Invalid or expired token, or password does not meet the policy"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/password-reset/confirm [post]
+func ConfirmPasswordReset(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.ConfirmPasswordResetRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if req.Token == "" || req.Password == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Token and password are required",
+			})
+		}
+
+		if err := auth.ValidatePassword(req.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to hash password",
+			})
+		}
+
+		if _, err := repository.ConfirmPasswordReset(requestDB(c, db), auth.HashToken(req.Token), hash); err != nil {
+			if errors.Is(err, repository.ErrPasswordResetInvalid) {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Password reset link is invalid or has expired",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to reset password",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Password has been reset, you can now log in",
+		})
+	}
+}
+
+// passwordResetData is what the password reset templates are rendered with
+type passwordResetData struct {
+	FullName  string
+	Username  string
+	ResetURL  string
+	ExpiresAt time.Time
+}
+
+// sendPasswordReset stores a new reset token for a user and emails the link.
+// The email is sent in the background so that known and unknown addresses
+// take about as long to answer; delivery problems are only logged.
+func sendPasswordReset(db *gorm.DB, user *models.User) error {
+	token, err := models.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+
+	reset := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(config.AppConfig.PasswordResetExpiryMinutes) * time.Minute),
+	}
+	if err := repository.CreatePasswordResetToken(db, &reset); err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(user.Email, "Reset your Life Beacon 360 password", mailer.TemplatePasswordReset, passwordResetData{
+		FullName:  user.FullName,
+		Username:  user.Username,
+		ResetURL:  config.AppConfig.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresAt: reset.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		// Send already logged a missing SMTP_HOST
+		if err := mailer.Send(msg); err != nil && !errors.Is(err, mailer.ErrNotConfigured) {
+			log.Printf("Error sending password reset to user %s: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
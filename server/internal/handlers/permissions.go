@@ -0,0 +1,386 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/permissions.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description Lists permission grants, optionally filtered by user, group or permission type (admin only)
+// @Tags Permissions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param user_id query string false "User ID"
+// @Param group_id query string false "Group ID"
+// @Param permission_type query string false "Permission type"
+// @Success 200 {array} models.Permission
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 500 {object} map[string]string
+// @Router /api/permissions [get]
+func ListPermissions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var filter repository.PermissionFilter
+
+		if value := c.QueryParam("user_id"); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid user ID",
+				})
+			}
+			filter.UserID = &id
+		}
+
+		if value := c.QueryParam("group_id"); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid group ID",
+				})
+			}
+			filter.GroupID = &id
+		}
+
+		filter.PermissionType = c.QueryParam("permission_type")
+
+		permissions, err := repository.ListPermissions(db, filter)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve permissions",
+			})
+		}
+
+		return c.JSON(http.StatusOK, permissions)
+	}
+}
+
+// GrantPermission godoc
+// @Summary Grant permission
+// @Description Grants a permission to a user or a group (admin only). Admins cannot be granted permissions.
+// @Tags Permissions
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param permission body models.GrantPermissionRequest true "Permission grant"
+// @Success 201 {object} models.Permission
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Permissions cannot be granted to admins"
+// @Failure 500 {object} map[string]string
+// @Router /api/permissions [post]
+func GrantPermission(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.GrantPermissionRequest
+
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if (req.UserID == nil) == (req.GroupID == nil) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Exactly one of user_id and group_id is required",
+			})
+		}
+
+		if !models.IsValidPermissionType(req.PermissionType) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid permission type",
+			})
+		}
+
+		if !models.IsValidTargetType(req.TargetType) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid target type",
+			})
+		}
+
+		if req.TargetType == models.TargetSpecificUsers {
+			if len(req.TargetUsers) == 0 {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "target_users is required for specific_users targets",
+				})
+			}
+
+			targetIDs := uniqueIDs(req.TargetUsers)
+			count, err := repository.CountExistingUsers(db, targetIDs)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to verify target users",
+				})
+			}
+			if count != int64(len(targetIDs)) {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "target_users contains unknown users",
+				})
+			}
+			req.TargetUsers = targetIDs
+		} else if len(req.TargetUsers) > 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "target_users is only allowed for specific_users targets",
+			})
+		}
+
+		if req.GroupID != nil {
+			if _, err := repository.GetGroupByID(db, *req.GroupID); err != nil {
+				return groupLookupError(c, err)
+			}
+		}
+
+		permission := models.Permission{
+			UserID:         req.UserID,
+			GroupID:        req.GroupID,
+			PermissionType: req.PermissionType,
+			TargetType:     req.TargetType,
+			TargetUsers:    models.UUIDList(req.TargetUsers),
+			GrantedBy:      middleware.CurrentUser(c).ID,
+		}
+
+		if err := repository.CreatePermission(requestDB(c, db), &permission); err != nil {
+			if errors.Is(err, repository.ErrAdminPermission) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Permissions cannot be granted to admins",
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to grant permission",
+			})
+		}
+
+		return c.JSON(http.StatusCreated, permission)
+	}
+}
+
+// RevokePermission godoc
+// @Summary Revoke permission
+// @Description Removes a single permission grant (admin only)
+// @Tags Permissions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Permission ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/permissions/{id} [delete]
+func RevokePermission(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		permissionID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid permission ID",
+			})
+		}
+
+		if err := repository.DeletePermission(requestDB(c, db), permissionID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Permission not found",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to revoke permission",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Permission revoked successfully",
+		})
+	}
+}
+
+// ResetUserPermissions godoc
+// @Summary Reset user permissions
+// @Description Removes every permission granted directly to a user (admin only)
+// @Tags Permissions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.ResetPermissionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/permissions [delete]
+func ResetUserPermissions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		if _, err := repository.GetUserByID(db, userID); err != nil {
+			return userLookupError(c, err)
+		}
+
+		deleted, err := repository.DeleteUserPermissions(requestDB(c, db), userID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to reset permissions",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.ResetPermissionsResponse{Deleted: deleted})
+	}
+}
+
+// ResetGroupPermissions godoc
+// @Summary Reset group permissions
+// @Description Removes every permission granted to a group (admin only)
+// @Tags Permissions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.ResetPermissionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id}/permissions [delete]
+func ResetGroupPermissions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		if _, err := repository.GetGroupByID(db, groupID); err != nil {
+			return groupLookupError(c, err)
+		}
+
+		deleted, err := repository.DeleteGroupPermissions(requestDB(c, db), groupID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to reset permissions",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.ResetPermissionsResponse{Deleted: deleted})
+	}
+}
+
+// UpdateUserRole godoc
+// @Summary Change user role
+// @Description Promotes a user to admin or demotes an admin to user (admin only). The last admin cannot be demoted.
+// @Tags Permissions
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role body models.UpdateRoleRequest true "New role"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "The last remaining admin cannot be demoted"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/role [put]
+func UpdateUserRole(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		var req models.UpdateRoleRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if !models.IsValidRole(req.Role) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid role",
+			})
+		}
+
+		if err := repository.UpdateUserRole(requestDB(c, db), userID, req.Role); err != nil {
+			if errors.Is(err, repository.ErrLastAdmin) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "The last remaining admin cannot be demoted",
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update role",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Role updated successfully",
+		})
+	}
+}
+
+// groupLookupError writes the response for a failed group lookup
+func groupLookupError(c echo.Context, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Group not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to retrieve group",
+	})
+}
+
+// uniqueIDs removes duplicate IDs while keeping their order
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
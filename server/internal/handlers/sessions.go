@@ -0,0 +1,264 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/sessions.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ListMySessions godoc
+// @Summary List own sessions
+// @Description Lists the active sessions of the authenticated user
+// @Tags Sessions
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} models.Session
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/sessions [get]
+func ListMySessions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+
+		sessions, err := repository.ListActiveSessions(db, user.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve sessions",
+			})
+		}
+
+		markCurrentSession(c, sessions)
+		return c.JSON(http.StatusOK, sessions)
+	}
+}
+
+// GetSession godoc
+// @Summary Inspect session
+// @Description Returns a single session. Users can inspect their own sessions, admins any session.
+// @Tags Sessions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.Session
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/sessions/{id} [get]
+func GetSession(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, status, message := findAccessibleSession(c, db)
+		if session == nil {
+			return c.JSON(status, map[string]string{
+				"error": message,
+			})
+		}
+
+		session.Current = session.ID == middleware.CurrentSession(c).ID
+		return c.JSON(http.StatusOK, session)
+	}
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Immediately terminates a session. Users can revoke their own sessions, admins any session.
+// @Tags Sessions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/sessions/{id} [delete]
+func RevokeSession(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, status, message := findAccessibleSession(c, db)
+		if session == nil {
+			return c.JSON(status, map[string]string{
+				"error": message,
+			})
+		}
+
+		if err := repository.RevokeSession(requestDB(c, db), session.ID, middleware.CurrentUser(c).ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to revoke session",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Session revoked successfully",
+		})
+	}
+}
+
+// RevokeOtherSessions godoc
+// @Summary Log out everywhere else
+// @Description Revokes all sessions of the authenticated user except the current one
+// @Tags Sessions
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} models.RevokeSessionsResponse
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/sessions/revoke-others [post]
+func RevokeOtherSessions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+		current := middleware.CurrentSession(c)
+
+		revoked, err := repository.RevokeUserSessions(requestDB(c, db), user.ID, current.ID, user.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to revoke sessions",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.RevokeSessionsResponse{Revoked: revoked})
+	}
+}
+
+// ListUserSessions godoc
+// @Summary List user sessions
+// @Description Lists the active sessions of any user (admin only)
+// @Tags Sessions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} models.Session
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/sessions [get]
+func ListUserSessions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		if _, err := repository.GetUserByID(db, userID); err != nil {
+			return userLookupError(c, err)
+		}
+
+		sessions, err := repository.ListActiveSessions(db, userID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve sessions",
+			})
+		}
+
+		markCurrentSession(c, sessions)
+		return c.JSON(http.StatusOK, sessions)
+	}
+}
+
+// RevokeUserSessions godoc
+// @Summary Revoke all user sessions
+// @Description Immediately terminates every active session of a user (admin only)
+// @Tags Sessions
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.RevokeSessionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/sessions [delete]
+func RevokeUserSessions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		if _, err := repository.GetUserByID(db, userID); err != nil {
+			return userLookupError(c, err)
+		}
+
+		revoked, err := repository.RevokeUserSessions(requestDB(c, db), userID, uuid.Nil, middleware.CurrentUser(c).ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to revoke sessions",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.RevokeSessionsResponse{Revoked: revoked})
+	}
+}
+
+// findAccessibleSession loads the session named by the :id path parameter and
+// checks that the caller owns it or is an admin. On failure it returns a nil
+// session together with the status and message to respond with.
+func findAccessibleSession(c echo.Context, db *gorm.DB) (*models.Session, int, string) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return nil, http.StatusBadRequest, "Invalid session ID"
+	}
+
+	session, err := repository.GetSessionByID(db, sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, http.StatusInternalServerError, "Failed to retrieve session"
+	}
+
+	// Other users' sessions are reported as missing rather than forbidden
+	user := middleware.CurrentUser(c)
+	if session == nil || (session.UserID != user.ID && !user.IsAdmin()) {
+		return nil, http.StatusNotFound, "Session not found"
+	}
+
+	return session, 0, ""
+}
+
+// markCurrentSession flags the session used to make the request
+func markCurrentSession(c echo.Context, sessions []models.Session) {
+	current := middleware.CurrentSession(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.ID
+	}
+}
+
+// userLookupError writes the response for a failed user lookup
+func userLookupError(c echo.Context, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "User not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to retrieve user",
+	})
+}
@@ -0,0 +1,433 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/settings.go
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/settings"
+	"gorm.io/gorm"
+)
+
+// GetGlobalSettings godoc
+// @Summary Get global settings
+// @Description Returns the system-wide default settings (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 500 {object} map[string]string
+// @Router /api/settings/global [get]
+func GetGlobalSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		global, err := repository.GetGlobalSettings(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve settings",
+			})
+		}
+
+		values, err := settings.GlobalValues(global)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve settings",
+			})
+		}
+
+		return c.JSON(http.StatusOK, values)
+	}
+}
+
+// UpdateGlobalSettings godoc
+// @Summary Update global settings
+// @Description Changes one or more system-wide default settings (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param settings body map[string]interface{} true "Settings to change, keyed by name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{} "Invalid settings with per-field errors"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 409 {object} map[string]string "Requiring two-factor authentication for admins without having it enabled"
+// @Failure 500 {object} map[string]string
+// @Router /api/settings/global [patch]
+func UpdateGlobalSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		values, ok, err := bindSettingValues(c, true)
+		if !ok {
+			return err
+		}
+
+		// Admins must not lock themselves out by requiring what they lack
+		user := middleware.CurrentUser(c)
+		if require, _ := values[settings.KeyRequire2FAForAdmins].(bool); require && !user.TwoFactorEnabled {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Enable two-factor authentication for your own account first",
+			})
+		}
+
+		global, err := repository.UpdateGlobalSettings(requestDB(c, db), values, &user.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update settings",
+			})
+		}
+		settings.NotifyChanged()
+
+		updated, err := settings.GlobalValues(global)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve settings",
+			})
+		}
+
+		return c.JSON(http.StatusOK, updated)
+	}
+}
+
+// GetGroupSettings godoc
+// @Summary Get group settings
+// @Description Returns the setting overrides of a group (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.GroupSettings
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id}/settings [get]
+func GetGroupSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		groupID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid group ID",
+			})
+		}
+
+		if _, err := repository.GetGroupByID(db, groupID); err != nil {
+			return groupLookupError(c, err)
+		}
+
+		groupSettings, err := repository.GetGroupSettings(db, groupID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve settings",
+			})
+		}
+
+		return c.JSON(http.StatusOK, groupSettings)
+	}
+}
+
+// UpdateGroupSettings godoc
+// @Summary Set group overrides
+// @Description Sets one or more setting overrides for a group; other overrides are kept (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param settings body map[string]interface{} true "Overrides to set, keyed by name"
+// @Success 200 {object} models.GroupSettings
+// @Failure 400 {object} map[string]interface{} "Invalid settings with per-field errors"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id}/settings [patch]
+func UpdateGroupSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return changeGroupSettings(c, db, false)
+	}
+}
+
+// ClearGroupSettings godoc
+// @Summary Clear group overrides
+// @Description Removes a single override (when a key is given) or all overrides of a group (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param key path string false "Setting key"
+// @Success 200 {object} models.GroupSettings
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/groups/{id}/settings/{key} [delete]
+func ClearGroupSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return changeGroupSettings(c, db, true)
+	}
+}
+
+// GetUserSettings godoc
+// @Summary Get user settings
+// @Description Returns the setting overrides of a user (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserSettings
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/settings [get]
+func GetUserSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		if _, err := repository.GetUserByID(db, userID); err != nil {
+			return userLookupError(c, err)
+		}
+
+		userSettings, err := repository.GetUserSettings(db, userID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve settings",
+			})
+		}
+
+		return c.JSON(http.StatusOK, userSettings)
+	}
+}
+
+// UpdateUserSettings godoc
+// @Summary Set user overrides
+// @Description Sets one or more setting overrides for a user; other overrides are kept (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param settings body map[string]interface{} true "Overrides to set, keyed by name"
+// @Success 200 {object} models.UserSettings
+// @Failure 400 {object} map[string]interface{} "Invalid settings with per-field errors"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/settings [patch]
+func UpdateUserSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return changeUserSettings(c, db, false)
+	}
+}
+
+// ClearUserSettings godoc
+// @Summary Clear user overrides
+// @Description Removes a single override (when a key is given) or all overrides of a user (admin only)
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param key path string false "Setting key"
+// @Success 200 {object} models.UserSettings
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/settings/{key} [delete]
+func ClearUserSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return changeUserSettings(c, db, true)
+	}
+}
+
+// GetEffectiveSettings godoc
+// @Summary Get effective settings
+// @Description Returns every setting as resolved for a user together with the level (global, group or user) it came from. Users can query themselves ("me"), admins anyone.
+// @Tags Settings
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID or 'me'"
+// @Success 200 {object} settings.Effective
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/settings/effective [get]
+func GetEffectiveSettings(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+
+		target := user
+		if c.Param("id") != "me" {
+			userID, err := uuid.Parse(c.Param("id"))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid user ID",
+				})
+			}
+
+			// Other users' settings are reported as missing rather than forbidden
+			if userID != user.ID && !user.IsAdmin() {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "User not found",
+				})
+			}
+
+			target, err = repository.GetUserByID(db, userID)
+			if err != nil {
+				return userLookupError(c, err)
+			}
+		}
+
+		effective, err := settings.Resolve(db, target)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to resolve settings",
+			})
+		}
+
+		return c.JSON(http.StatusOK, effective)
+	}
+}
+
+// changeGroupSettings sets overrides from the request body, or clears the
+// override named by the :key path parameter (all overrides without one)
+func changeGroupSettings(c echo.Context, db *gorm.DB, clear bool) error {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid group ID",
+		})
+	}
+
+	values, keys, clearAll, ok, err := settingsChange(c, clear)
+	if !ok {
+		return err
+	}
+
+	if _, err := repository.GetGroupByID(db, groupID); err != nil {
+		return groupLookupError(c, err)
+	}
+
+	groupSettings, err := repository.UpdateGroupSettings(requestDB(c, db), groupID, values, keys, clearAll, &middleware.CurrentUser(c).ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update settings",
+		})
+	}
+	settings.NotifyChanged()
+
+	return c.JSON(http.StatusOK, groupSettings)
+}
+
+// changeUserSettings is the user counterpart of changeGroupSettings
+func changeUserSettings(c echo.Context, db *gorm.DB, clear bool) error {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid user ID",
+		})
+	}
+
+	values, keys, clearAll, ok, err := settingsChange(c, clear)
+	if !ok {
+		return err
+	}
+
+	if _, err := repository.GetUserByID(db, userID); err != nil {
+		return userLookupError(c, err)
+	}
+
+	userSettings, err := repository.UpdateUserSettings(requestDB(c, db), userID, values, keys, clearAll, &middleware.CurrentUser(c).ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update settings",
+		})
+	}
+	settings.NotifyChanged()
+
+	return c.JSON(http.StatusOK, userSettings)
+}
+
+// settingsChange works out what a set or clear request asks for. When ok is
+// false the error response has already been written and err must be returned.
+func settingsChange(c echo.Context, clear bool) (values map[string]interface{}, keys []string, clearAll bool, ok bool, err error) {
+	if !clear {
+		values, ok, err = bindSettingValues(c, false)
+		return values, nil, false, ok, err
+	}
+
+	key := c.Param("key")
+	if key == "" {
+		return nil, nil, true, true, nil
+	}
+	if !settings.IsOverridable(key) {
+		return nil, nil, false, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Unknown setting",
+		})
+	}
+	return nil, []string{key}, false, true, nil
+}
+
+// bindSettingValues decodes and validates a JSON object of settings keyed by
+// name; global-only settings are accepted when global is set. When ok is
+// false the error response has already been written and err must be returned.
+func bindSettingValues(c echo.Context, global bool) (map[string]interface{}, bool, error) {
+	var raw map[string]interface{}
+
+	decoder := json.NewDecoder(c.Request().Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to parse JSON",
+		})
+	}
+
+	if len(raw) == 0 {
+		return nil, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "No settings given",
+		})
+	}
+
+	values, fieldErrors := settings.Validate(raw, global)
+	if fieldErrors != nil {
+		return nil, false, c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Invalid settings",
+			"fields": fieldErrors,
+		})
+	}
+
+	return values, true, nil
+}
@@ -0,0 +1,151 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/setup.go
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// GetSetupStatus godoc
+// @Summary Get setup status
+// @Description Reports whether the first-run setup wizard still has to be completed
+// @Tags Setup
+// @Produce json
+// @Success 200 {object} models.SetupStatusResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/setup/status [get]
+func GetSetupStatus(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		required, err := repository.IsSetupRequired(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to check setup status",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.SetupStatusResponse{
+			SetupRequired: required,
+		})
+	}
+}
+
+// RunSetup godoc
+// @Summary Run first-time setup
+// @Description Creates the "Main" group and the initial admin. Only available while the database has no users.
+// @Tags Setup
+// @Accept json
+// @Produce json
+// @Param setup body models.SetupRequest true "Initial admin account"
+// @Success 201 {object} models.SetupResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "Setup has already been completed"
+// @Failure 500 {object} map[string]string
+// @Router /api/setup [post]
+func RunSetup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var setupReq models.SetupRequest
+
+		// Parse JSON body into SetupRequest struct
+		if err := c.Bind(&setupReq); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		setupReq.Username = strings.TrimSpace(setupReq.Username)
+		setupReq.FullName = strings.TrimSpace(setupReq.FullName)
+		setupReq.Phone = strings.TrimSpace(setupReq.Phone)
+
+		if setupReq.Username == "" || setupReq.Email == "" || setupReq.Password == "" ||
+			setupReq.FullName == "" || setupReq.Phone == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Username, email, password, full name and phone are required",
+			})
+		}
+
+		if _, err := mail.ParseAddress(setupReq.Email); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid email address",
+			})
+		}
+
+		if err := auth.ValidatePassword(setupReq.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		// Fail fast before spending time on hashing
+		required, err := repository.IsSetupRequired(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to check setup status",
+			})
+		}
+		if !required {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Setup has already been completed",
+			})
+		}
+
+		hash, err := auth.HashPassword(setupReq.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to hash password",
+			})
+		}
+
+		group := models.Group{
+			Name: models.DefaultGroupName,
+		}
+		admin := models.User{
+			Username:     setupReq.Username,
+			Email:        setupReq.Email,
+			PasswordHash: hash,
+			Role:         models.RoleAdmin,
+			FullName:     setupReq.FullName,
+			Phone:        setupReq.Phone,
+		}
+
+		if err := repository.CompleteSetup(requestDB(c, db), &group, &admin); err != nil {
+			if errors.Is(err, repository.ErrSetupCompleted) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Setup has already been completed",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to complete setup",
+			})
+		}
+
+		return c.JSON(http.StatusCreated, models.SetupResponse{
+			User:  admin,
+			Group: group,
+		})
+	}
+}
@@ -0,0 +1,387 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/tracking.go
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/settings"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxTrackingConfigWait caps how long a long-poll request is held open
+	maxTrackingConfigWait = 60 * time.Second
+	// trackingConfigRecheckInterval is how often a held request re-reads the
+	// configuration to notice changes made by other server instances
+	trackingConfigRecheckInterval = 5 * time.Second
+)
+
+// GetTrackingConfig godoc
+// @Summary Get tracking configuration
+// @Description Returns the tracking configuration resolved for the current user. Send the last ETag in If-None-Match to get 304 when nothing changed; add wait (e.g. 60s, at most 60s) to hold the request until the configuration changes or the wait runs out.
+// @Tags Tracking
+// @Security ApiKeyAuth
+// @Produce json
+// @Param If-None-Match header string false "ETag of the configuration the client already has"
+// @Param wait query string false "Long-poll duration, e.g. 30s"
+// @Success 200 {object} models.TrackingConfig
+// @Success 304 "Configuration unchanged"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/me/tracking-config [get]
+func GetTrackingConfig(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		wait, err := parseWait(c.QueryParam("wait"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid wait duration",
+			})
+		}
+
+		ctx := c.Request().Context()
+		known := c.Request().Header.Get("If-None-Match")
+		deadline := time.NewTimer(wait)
+		defer deadline.Stop()
+
+		user := middleware.CurrentUser(c)
+		for {
+			// Subscribe before reading so a change in between is not missed
+			changed := settings.Changed()
+
+			config, etag, err := loadTrackingConfig(requestDB(c, db), user)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to retrieve tracking configuration",
+				})
+			}
+
+			c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+			c.Response().Header().Set("ETag", etag)
+			if !etagMatches(known, etag) {
+				return c.JSON(http.StatusOK, config)
+			}
+			if wait == 0 {
+				return c.NoContent(http.StatusNotModified)
+			}
+
+			select {
+			case <-changed:
+			case <-time.After(trackingConfigRecheckInterval):
+			case <-deadline.C:
+				return c.NoContent(http.StatusNotModified)
+			case <-ctx.Done():
+				// The client went away; nobody is left to answer
+				return nil
+			}
+
+			// Group membership may have changed while waiting
+			user, err = repository.GetUserByID(db, user.ID)
+			if err != nil {
+				return userLookupError(c, err)
+			}
+		}
+	}
+}
+
+// ControlTracking godoc
+// @Summary Start or stop tracking
+// @Description Switches tracking of a user on or off. Controlling another user requires can_control_tracking for that user; controlling yourself ("me") requires can_control_own_tracking, and stopping is refused while tracking is mandatory. Every request is kept in the tracking control log.
+// @Tags Tracking
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID or 'me'"
+// @Param request body models.TrackingControlRequest true "start or stop"
+// @Success 200 {object} models.TrackingControlLog
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission or tracking is mandatory"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/tracking [post]
+func ControlTracking(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.TrackingControlRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if !models.IsValidTrackingAction(req.Action) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Action must be start or stop",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+		target := user
+		if c.Param("id") != "me" {
+			targetID, err := uuid.Parse(c.Param("id"))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid user ID",
+				})
+			}
+
+			target, err = repository.GetUserByID(db, targetID)
+			if err != nil {
+				return userLookupError(c, err)
+			}
+		}
+
+		permissionType := models.PermissionControlTracking
+		if target.ID == user.ID {
+			permissionType = models.PermissionControlOwnTracking
+		}
+
+		allowed, err := auth.HasPermission(db, user, permissionType, target)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to check permissions",
+			})
+		}
+		if !allowed {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error":      "Forbidden - Missing permission",
+				"permission": permissionType,
+			})
+		}
+
+		enabled := req.Action == models.TrackingActionStart
+		if !enabled && target.ID == user.ID && !user.IsAdmin() {
+			effective, err := settings.Resolve(db, user)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to resolve settings",
+				})
+			}
+			if effective.Bool(settings.KeyMandatoryTracking) {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Forbidden - Tracking is mandatory",
+				})
+			}
+		}
+
+		entry, err := repository.SetTrackingEnabled(requestDB(c, db), target.ID, enabled, user.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update tracking state",
+			})
+		}
+		settings.NotifyChanged()
+
+		return c.JSON(http.StatusOK, entry)
+	}
+}
+
+// ListTrackingControlLog godoc
+// @Summary List tracking control log
+// @Description Lists start and stop requests for a user, newest first. Requires can_control_tracking for that user.
+// @Tags Tracking
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID or 'me'"
+// @Param page query int false "Page number (default 1)"
+// @Param per_page query int false "Entries per page (default 50, max 200)"
+// @Success 200 {object} models.Page[models.TrackingControlLog]
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/tracking/log [get]
+func ListTrackingControlLog(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		page, perPage, err := parsePagination(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		target := middleware.TargetUser(c)
+		entries, total, err := repository.ListTrackingControlLogs(db, target.ID, (page-1)*perPage, perPage)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve tracking control log",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.Page[models.TrackingControlLog]{
+			Items:   entries,
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+		})
+	}
+}
+
+// SetSelfTrackingControl godoc
+// @Summary Allow or forbid self tracking control
+// @Description Grants or revokes a user's own can_control_own_tracking permission (admin only). Grants made to the user's group still apply; the response reports the resulting decision and its source.
+// @Tags Tracking
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.SelfTrackingControlRequest true "Whether the user may control their own tracking"
+// @Success 200 {object} models.SelfTrackingControlResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Admins always control their own tracking"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/tracking/self-control [put]
+func SetSelfTrackingControl(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		var req models.SelfTrackingControlRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if req.Allowed == nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Allowed is required",
+			})
+		}
+
+		err = repository.SetSelfTrackingControl(requestDB(c, db), userID, *req.Allowed, middleware.CurrentUser(c).ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrAdminPermission) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Admins always control their own tracking",
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update permissions",
+			})
+		}
+
+		target, err := repository.GetUserByID(db, userID)
+		if err != nil {
+			return userLookupError(c, err)
+		}
+
+		decision, err := auth.ResolvePermission(db, target, models.PermissionControlOwnTracking, target)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to check permissions",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.SelfTrackingControlResponse{
+			Allowed: decision.Allowed,
+			Source:  decision.Source,
+		})
+	}
+}
+
+// loadTrackingConfig resolves the tracking configuration of a user and
+// returns it together with its ETag
+func loadTrackingConfig(db *gorm.DB, user *models.User) (*models.TrackingConfig, string, error) {
+	effective, err := settings.Resolve(db, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	userSettings, err := repository.GetUserSettings(db, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	config := &models.TrackingConfig{
+		TrackingEnabled:      userSettings.TrackingEnabled,
+		TrackingInterval:     effective.Int(settings.KeyTrackingInterval),
+		PollingInterval:      effective.Int(settings.KeyPollingInterval),
+		AccuracyMode:         effective.String(settings.KeyAccuracyMode),
+		MandatoryTracking:    effective.Bool(settings.KeyMandatoryTracking),
+		BatteryLowThreshold:  effective.Int(settings.KeyBatteryLowThreshold),
+		BatteryStopThreshold: effective.Int(settings.KeyBatteryStopThreshold),
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(b)
+
+	return config, `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// parseWait parses the long-poll duration, either as a Go duration ("30s") or
+// as a number of seconds, and caps it at maxTrackingConfigWait
+func parseWait(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, err
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	if wait < 0 {
+		return 0, strconv.ErrRange
+	}
+	return min(wait, maxTrackingConfigWait), nil
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
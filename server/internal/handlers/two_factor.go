@@ -0,0 +1,637 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/two_factor.go
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// loginChallengeLifetime is how long the second login step may take
+	loginChallengeLifetime = 5 * time.Minute
+
+	// maxLoginChallengeAttempts is how many wrong codes a challenge survives
+	maxLoginChallengeAttempts = 5
+)
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Answers the challenge returned by login with a TOTP code or a recovery code and creates the session. When the challenge requires setup, the code confirms the secret from /api/auth/login/2fa/setup and the response includes the new recovery codes.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginTwoFactorRequest true "Challenge token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid code or challenge"
+// @Failure 429 {object} map[string]interface{} "Too many failed attempts"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/login/2fa [post]
+func LoginTwoFactor(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.LoginTwoFactorRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if req.Code == "" && req.RecoveryCode == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Code or recovery code is required",
+			})
+		}
+
+		challenge, user, ok, err := loadLoginChallenge(c, db, req.ChallengeToken)
+		if !ok {
+			return err
+		}
+
+		policy, err := auth.LoadLockoutPolicy(db)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify code",
+			})
+		}
+		if ok, err := checkLoginThrottle(c, db, policy,
+			auth.AccountThrottleKey(user.Username), auth.IPThrottleKey(c.RealIP())); !ok {
+			return err
+		}
+
+		var recoveryCodes []string
+		if user.TwoFactorEnabled {
+			ok, err = verifySecondFactor(requestDB(c, db), user, req.Code, req.RecoveryCode)
+		} else if req.Code != "" {
+			recoveryCodes, err = confirmEnrollment(requestDB(c, db), user, req.Code)
+			ok = err == nil
+			if errors.Is(err, errInvalidCode) || errors.Is(err, repository.ErrTwoFactorNotPending) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify code",
+			})
+		}
+
+		if !ok {
+			if err := repository.RecordLoginChallengeFailure(db, challenge.ID); err != nil {
+				log.Printf("Error recording login challenge failure for user %s: %v", user.ID, err)
+			}
+			recordLoginFailure(c, db, policy, user.Username, &user.ID)
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Unauthorized - Invalid verification code",
+			})
+		}
+
+		consumed, err := repository.ConsumeLoginChallenge(db, challenge.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to create session",
+			})
+		}
+		if !consumed {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Unauthorized - Login challenge is invalid or has expired",
+			})
+		}
+
+		user.TwoFactorEnabled = true
+		return startSession(c, db, user, challenge.ClientType, recoveryCodes)
+	}
+}
+
+// LoginTwoFactorSetup godoc
+// @Summary Enroll in two-factor authentication during login
+// @Description For login challenges that require setup: creates a TOTP secret to add to an authenticator app. The login is then completed at /api/auth/login/2fa with a code from the app.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginChallengeRequest true "Challenge token"
+// @Success 200 {object} models.TwoFactorSetupResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid challenge"
+// @Failure 409 {object} map[string]string "Two-factor authentication is already enabled"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/login/2fa/setup [post]
+func LoginTwoFactorSetup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.LoginChallengeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		_, user, ok, err := loadLoginChallenge(c, db, req.ChallengeToken)
+		if !ok {
+			return err
+		}
+
+		return beginEnrollment(c, db, user)
+	}
+}
+
+// GetTwoFactorStatus godoc
+// @Summary Get two-factor status
+// @Description Returns whether two-factor authentication is enabled for the authenticated user, whether it is required, and how many recovery codes are left
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} models.TwoFactorStatus
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa [get]
+func GetTwoFactorStatus(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+
+		required, err := twoFactorRequired(db, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve two-factor status",
+			})
+		}
+
+		remaining, err := repository.CountUnusedRecoveryCodes(db, user.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve two-factor status",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.TwoFactorStatus{
+			Enabled:                user.TwoFactorEnabled,
+			Required:               required,
+			RecoveryCodesRemaining: remaining,
+		})
+	}
+}
+
+// SetupTwoFactor godoc
+// @Summary Start two-factor enrollment
+// @Description Creates a new TOTP secret for the authenticated user. It only takes effect once confirmed at /api/auth/2fa/enable.
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} models.TwoFactorSetupResponse
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 409 {object} map[string]string "Two-factor authentication is already enabled"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/setup [post]
+func SetupTwoFactor(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return beginEnrollment(c, db, middleware.CurrentUser(c))
+	}
+}
+
+// EnableTwoFactor godoc
+// @Summary Enable two-factor authentication
+// @Description Confirms the secret from /api/auth/2fa/setup with a code from the authenticator app and returns recovery codes. They are shown only once.
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} map[string]string "Invalid code or no enrollment started"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 409 {object} map[string]string "Two-factor authentication is already enabled"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/enable [post]
+func EnableTwoFactor(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.TwoFactorCodeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+		if user.TwoFactorEnabled {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Two-factor authentication is already enabled",
+			})
+		}
+
+		if req.Code == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Code is required",
+			})
+		}
+
+		codes, err := confirmEnrollment(requestDB(c, db), user, req.Code)
+		if err != nil {
+			switch {
+			case errors.Is(err, errInvalidCode):
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid verification code",
+				})
+			case errors.Is(err, repository.ErrTwoFactorNotPending):
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Start two-factor setup first",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to enable two-factor authentication",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turns off two-factor authentication for the authenticated user after verifying the password and a TOTP or recovery code. Admins cannot turn it off while it is required for them.
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.DisableTwoFactorRequest true "Password and code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Wrong password or code, or two-factor authentication is required"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/disable [post]
+func DisableTwoFactor(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.DisableTwoFactorRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+		if !user.TwoFactorEnabled {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Two-factor authentication is not enabled",
+			})
+		}
+
+		required, err := twoFactorRequired(db, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to disable two-factor authentication",
+			})
+		}
+		if required {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Two-factor authentication is required for admins",
+			})
+		}
+
+		passwordOK, _, err := auth.VerifyPassword(user.PasswordHash, req.Password)
+		if err != nil {
+			log.Printf("Error verifying password for user %s: %v", user.ID, err)
+		}
+		if !passwordOK {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Password is incorrect",
+			})
+		}
+
+		ok, err := verifySecondFactor(requestDB(c, db), user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify code",
+			})
+		}
+		if !ok {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Invalid verification code",
+			})
+		}
+
+		if err := repository.DisableTwoFactor(requestDB(c, db), user.ID, audit.ActionUser2FADisable); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to disable two-factor authentication",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Two-factor authentication disabled",
+		})
+	}
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes of the authenticated user after verifying a TOTP or recovery code. The new codes are shown only once.
+// @Tags Auth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Invalid code"
+// @Failure 500 {object} map[string]string
+// @Router /api/auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.TwoFactorCodeRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+		if !user.TwoFactorEnabled {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Two-factor authentication is not enabled",
+			})
+		}
+
+		ok, err := verifySecondFactor(requestDB(c, db), user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify code",
+			})
+		}
+		if !ok {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Invalid verification code",
+			})
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err == nil {
+			err = repository.RegenerateRecoveryCodes(requestDB(c, db), user.ID, hashes)
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to regenerate recovery codes",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// ResetUserTwoFactor godoc
+// @Summary Reset a user's two-factor authentication
+// @Description Removes the TOTP secret and recovery codes of a user who lost their authenticator app (admin only). If two-factor authentication is required for them, they enroll again at their next login.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/2fa [delete]
+func ResetUserTwoFactor(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		if _, err := repository.GetUserByID(db, userID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "User not found",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve user",
+			})
+		}
+
+		if err := repository.DisableTwoFactor(requestDB(c, db), userID, audit.ActionUser2FAReset); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to reset two-factor authentication",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Two-factor authentication reset",
+		})
+	}
+}
+
+// errInvalidCode is returned by confirmEnrollment for a wrong TOTP code
+var errInvalidCode = errors.New("invalid verification code")
+
+// twoFactorRequired reports whether the global settings require a user to
+// use two-factor authentication
+func twoFactorRequired(db *gorm.DB, user *models.User) (bool, error) {
+	if !user.IsAdmin() {
+		return false, nil
+	}
+	global, err := repository.GetGlobalSettings(db)
+	if err != nil {
+		return false, err
+	}
+	return global.Require2FAForAdmins, nil
+}
+
+// startLoginChallenge answers a login with valid credentials with a challenge
+// for the second factor instead of a session
+func startLoginChallenge(c echo.Context, db *gorm.DB, user *models.User, clientType string) error {
+	response, err := createLoginChallenge(db, user, clientType)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create login challenge",
+		})
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// createLoginChallenge stores a challenge for the second login step
+func createLoginChallenge(db *gorm.DB, user *models.User, clientType string) (*models.TwoFactorChallengeResponse, error) {
+	token, err := models.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := models.LoginChallenge{
+		UserID:     user.ID,
+		TokenHash:  auth.HashToken(token),
+		ClientType: clientType,
+		ExpiresAt:  time.Now().Add(loginChallengeLifetime),
+	}
+	if err := repository.CreateLoginChallenge(db, &challenge); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		SetupRequired:     !user.TwoFactorEnabled,
+		ChallengeToken:    token,
+		ExpiresAt:         challenge.ExpiresAt,
+	}, nil
+}
+
+// loadLoginChallenge looks up a usable login challenge and its user. When ok
+// is false the error response has already been written and err must be
+// returned.
+func loadLoginChallenge(c echo.Context, db *gorm.DB, token string) (*models.LoginChallenge, *models.User, bool, error) {
+	invalid := func() (*models.LoginChallenge, *models.User, bool, error) {
+		return nil, nil, false, c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized - Login challenge is invalid or has expired",
+		})
+	}
+
+	if token == "" {
+		return nil, nil, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Challenge token is required",
+		})
+	}
+
+	challenge, err := repository.GetLoginChallenge(db, auth.HashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalid()
+	}
+	if err != nil {
+		return nil, nil, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to verify login challenge",
+		})
+	}
+	if !challenge.IsUsable(time.Now(), maxLoginChallengeAttempts) {
+		return invalid()
+	}
+
+	user, err := repository.GetUserByID(db, challenge.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalid()
+	}
+	if err != nil {
+		return nil, nil, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to verify login challenge",
+		})
+	}
+	if !user.IsActive {
+		return invalid()
+	}
+
+	return challenge, user, true, nil
+}
+
+// beginEnrollment creates a pending TOTP secret for a user and writes it as
+// the response
+func beginEnrollment(c echo.Context, db *gorm.DB, user *models.User) error {
+	if user.TwoFactorEnabled {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Two-factor authentication is already enabled",
+		})
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err == nil {
+		err = repository.StartTwoFactorEnrollment(db, user.ID, secret)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to start two-factor setup",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, user.Username),
+	})
+}
+
+// confirmEnrollment enables two-factor authentication for a user once a code
+// matches their pending secret and returns the new recovery codes
+func confirmEnrollment(db *gorm.DB, user *models.User, code string) ([]string, error) {
+	secret, err := repository.GetTwoFactorSecret(db, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrTwoFactorNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := auth.VerifyTOTP(secret.Secret, code, time.Now(), secret.LastUsedStep)
+	if !ok {
+		return nil, errInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.EnableTwoFactor(db, user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code when no TOTP
+// code is given, for a user with two-factor authentication enabled. Used
+// codes cannot be used again.
+func verifySecondFactor(db *gorm.DB, user *models.User, code, recoveryCode string) (bool, error) {
+	if code == "" {
+		if recoveryCode == "" {
+			return false, nil
+		}
+		return repository.UseRecoveryCode(db, user.ID, auth.HashRecoveryCode(recoveryCode))
+	}
+
+	secret, err := repository.GetTwoFactorSecret(db, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.VerifyTOTP(secret.Secret, code, time.Now(), secret.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+	return repository.UseTOTPStep(db, user.ID, step)
+}
+
+// newRecoveryCodes generates recovery codes and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
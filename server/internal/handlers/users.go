@@ -0,0 +1,512 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/api/handlers/users.go
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/middleware"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/settings"
+	"gorm.io/gorm"
+)
+
+// ListUsers godoc
+// @Summary List users
+// @Description Lists the users the caller may manage, ordered by username. Requires can_manage_users.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Param group_id query string false "Only members of this group"
+// @Param role query string false "Only users with this role (admin or user)"
+// @Param is_active query bool false "Only active or only deactivated users"
+// @Param search query string false "Matches username, email and full name"
+// @Param deleted query bool false "List soft-deleted users instead (admin only)"
+// @Param page query int false "Page number (default 1)"
+// @Param per_page query int false "Users per page (default 50, max 200)"
+// @Success 200 {object} models.Page[models.User]
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 500 {object} map[string]string
+// @Router /api/users [get]
+func ListUsers(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		filter, err := parseUserFilter(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		if filter.Deleted && !middleware.CurrentUser(c).IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Admin role required",
+			})
+		}
+
+		page, perPage, err := parsePagination(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		scope, err := auth.ResolveScope(db, middleware.CurrentUser(c), models.PermissionManageUsers)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to check permissions",
+			})
+		}
+		filter.Scope = &scope
+
+		users, total, err := repository.ListUsers(db, filter, (page-1)*perPage, perPage)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve users",
+			})
+		}
+
+		return c.JSON(http.StatusOK, models.Page[models.User]{
+			Items:   users,
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+		})
+	}
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Description Returns a single user. Requires can_manage_users for that user.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID or 'me'"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Router /api/users/{id} [get]
+func GetUser(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, middleware.TargetUser(c))
+	}
+}
+
+// CreateUser godoc
+// @Summary Create a user
+// @Description Creates a user and emails them an invitation with a temporary password (generated unless given) and a link to set their own. The user has to change the password on first login. Requires can_manage_users for the group the user is created in; only admins can create admins.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param user body models.CreateUserRequest true "New user"
+// @Success 201 {object} models.CreateUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string "Group not found"
+// @Failure 409 {object} map[string]string "Username is already taken"
+// @Failure 500 {object} map[string]string
+// @Router /api/users [post]
+func CreateUser(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req models.CreateUserRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		req.Username = strings.TrimSpace(req.Username)
+		req.FullName = strings.TrimSpace(req.FullName)
+		req.Phone = strings.TrimSpace(req.Phone)
+		req.JobTitle = strings.TrimSpace(req.JobTitle)
+
+		if req.Username == "" || req.Email == "" || req.FullName == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Username, email and full name are required",
+			})
+		}
+
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid email address",
+			})
+		}
+
+		if req.Role == "" {
+			req.Role = models.RoleUser
+		}
+		if !models.IsValidRole(req.Role) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid role",
+			})
+		}
+
+		user := middleware.CurrentUser(c)
+		if req.Role == models.RoleAdmin && !user.IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Only admins can create admins",
+			})
+		}
+
+		groupID := user.GroupID
+		if req.GroupID != nil {
+			groupID = *req.GroupID
+		}
+		if ok, err := checkManagedGroup(c, db, user, groupID); !ok {
+			return err
+		}
+
+		// Without a password from the creator a temporary one is generated
+		if req.Password == "" {
+			password, err := auth.GenerateTemporaryPassword()
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to generate password",
+				})
+			}
+			req.Password = password
+		} else if err := auth.ValidatePassword(req.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to hash password",
+			})
+		}
+
+		newUser := models.User{
+			GroupID:      groupID,
+			Username:     req.Username,
+			Email:        req.Email,
+			PasswordHash: hash,
+			Role:         req.Role,
+			FullName:     req.FullName,
+			Phone:        req.Phone,
+			JobTitle:     req.JobTitle,
+			FirstLogin:   true,
+		}
+
+		if err := repository.CreateUser(requestDB(c, db), &newUser); err != nil {
+			if errors.Is(err, repository.ErrUsernameTaken) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Username is already taken",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to create user",
+			})
+		}
+
+		invitation, err := inviteUser(c, db, &newUser, req.Password, "")
+		if err != nil {
+			// The account exists; the invitation can be resent later
+			log.Printf("Error creating invitation for user %s: %v", newUser.ID, err)
+			invitation = &models.InvitationResponse{}
+		}
+
+		return c.JSON(http.StatusCreated, models.CreateUserResponse{
+			User:               newUser,
+			InvitationResponse: *invitation,
+		})
+	}
+}
+
+// UpdateUser godoc
+// @Summary Update a user
+// @Description Changes profile fields, the group or the active state of a user; omitted fields are kept. Requires can_manage_users for the user (and for the new group when moving them); only admins can change admins. Deactivating a user ends all of their sessions.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param user body models.UpdateUserRequest true "Fields to change"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "The last remaining admin cannot be deactivated"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id} [patch]
+func UpdateUser(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+		target := middleware.TargetUser(c)
+
+		var req models.UpdateUserRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to parse JSON",
+			})
+		}
+
+		if target.IsAdmin() && !user.IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Only admins can change admins",
+			})
+		}
+
+		updates := map[string]interface{}{}
+		if req.Email != nil {
+			if _, err := mail.ParseAddress(*req.Email); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid email address",
+				})
+			}
+			updates["email"] = *req.Email
+		}
+		if req.FullName != nil {
+			fullName := strings.TrimSpace(*req.FullName)
+			if fullName == "" {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Full name cannot be empty",
+				})
+			}
+			updates["full_name"] = fullName
+		}
+		if req.Phone != nil {
+			updates["phone"] = strings.TrimSpace(*req.Phone)
+		}
+		if req.JobTitle != nil {
+			updates["job_title"] = strings.TrimSpace(*req.JobTitle)
+		}
+		if req.GroupID != nil && *req.GroupID != target.GroupID {
+			if ok, err := checkManagedGroup(c, db, user, *req.GroupID); !ok {
+				return err
+			}
+			updates["group_id"] = *req.GroupID
+		}
+		if req.IsActive != nil {
+			if !*req.IsActive && target.ID == user.ID {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "You cannot deactivate yourself",
+				})
+			}
+			updates["is_active"] = *req.IsActive
+		}
+
+		if len(updates) == 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "No changes given",
+			})
+		}
+
+		updated, err := repository.UpdateUser(requestDB(c, db), target.ID, updates, user.ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrLastAdmin) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "The last remaining admin cannot be deactivated",
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update user",
+			})
+		}
+
+		// A user in another group resolves their settings differently
+		if _, moved := updates["group_id"]; moved {
+			settings.NotifyChanged()
+		}
+
+		return c.JSON(http.StatusOK, updated)
+	}
+}
+
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description Soft-deletes a user and ends their sessions; admins can restore the user until it is purged. Requires can_manage_users for the user; only admins can delete admins, and nobody can delete themselves.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Missing permission"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "The last remaining admin cannot be deleted"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id} [delete]
+func DeleteUser(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := middleware.CurrentUser(c)
+		target := middleware.TargetUser(c)
+
+		if target.ID == user.ID {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "You cannot delete yourself",
+			})
+		}
+
+		if target.IsAdmin() && !user.IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Only admins can delete admins",
+			})
+		}
+
+		if err := repository.DeleteUser(requestDB(c, db), target.ID, user.ID); err != nil {
+			if errors.Is(err, repository.ErrLastAdmin) {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "The last remaining admin cannot be deleted",
+				})
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userLookupError(c, err)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to delete user",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "User deleted successfully",
+		})
+	}
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Brings back a soft-deleted user (admin only). The user's group has to be restored first if it was deleted too.
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "The user's group has been deleted"
+// @Failure 500 {object} map[string]string
+// @Router /api/users/{id}/restore [post]
+func RestoreUser(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid user ID",
+			})
+		}
+
+		user, err := repository.RestoreUser(requestDB(c, db), userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrGroupDeleted):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "The user's group has been deleted; restore it first",
+				})
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Deleted user not found",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to restore user",
+			})
+		}
+
+		return c.JSON(http.StatusOK, user)
+	}
+}
+
+// checkManagedGroup makes sure a group exists and that the user may manage
+// its members. When ok is false the error response has already been written
+// and err must be returned.
+func checkManagedGroup(c echo.Context, db *gorm.DB, user *models.User, groupID uuid.UUID) (bool, error) {
+	if _, err := repository.GetGroupByID(db, groupID); err != nil {
+		return false, groupLookupError(c, err)
+	}
+
+	// A user that does not exist yet stands in for any member of the group
+	allowed, err := auth.HasPermission(db, user, models.PermissionManageUsers, &models.User{GroupID: groupID})
+	if err != nil {
+		return false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to check permissions",
+		})
+	}
+	if !allowed {
+		return false, c.JSON(http.StatusForbidden, map[string]string{
+			"error":      "Forbidden - Missing permission",
+			"permission": models.PermissionManageUsers,
+		})
+	}
+
+	return true, nil
+}
+
+// parseUserFilter reads the user list filters from the query string
+func parseUserFilter(c echo.Context) (repository.UserFilter, error) {
+	var filter repository.UserFilter
+
+	if value := c.QueryParam("group_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return filter, errors.New("invalid group_id")
+		}
+		filter.GroupID = &id
+	}
+
+	if value := c.QueryParam("role"); value != "" {
+		if !models.IsValidRole(value) {
+			return filter, errors.New("invalid role")
+		}
+		filter.Role = value
+	}
+
+	if value := c.QueryParam("is_active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("invalid is_active")
+		}
+		filter.IsActive = &active
+	}
+
+	if value := c.QueryParam("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("invalid deleted")
+		}
+		filter.Deleted = deleted
+	}
+
+	filter.Search = strings.TrimSpace(c.QueryParam("search"))
+
+	return filter, nil
+}
@@ -0,0 +1,96 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/jobs/purge.go
+
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// purgeInterval is how often soft-deleted rows are checked for purging
+const purgeInterval = time.Hour
+
+// StartPurge removes users and groups that have been soft-deleted for longer
+// than retention, together with stale password reset and login data, once
+// right away and then every hour, until the process exits. A zero retention
+// disables purging of users and groups.
+func StartPurge(db *gorm.DB, retention time.Duration) {
+	if retention <= 0 {
+		log.Println("Purging of deleted users and groups is disabled")
+	}
+
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			if retention > 0 {
+				purgeDeleted(db, retention)
+			}
+			purgeAuthData(db)
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeDeleted runs a single purge pass. Users go first so that groups they
+// were the last members of can be purged in the same pass.
+func purgeDeleted(db *gorm.DB, retention time.Duration) {
+	cutoff := time.Now().Add(-retention)
+
+	users, err := repository.PurgeDeletedUsers(db, cutoff)
+	if err != nil {
+		log.Printf("Error purging deleted users: %v", err)
+	}
+
+	groups, err := repository.PurgeDeletedGroups(db, cutoff)
+	if err != nil {
+		log.Printf("Error purging deleted groups: %v", err)
+	}
+
+	if users > 0 || groups > 0 {
+		log.Printf("Purged %d deleted users and %d deleted groups", users, groups)
+	}
+}
+
+// staleResetAge is how long expired password reset tokens and reset requests
+// are kept; it has to exceed the one-hour rate limit window
+const staleResetAge = 24 * time.Hour
+
+// purgeAuthData removes expired password reset tokens, old reset requests,
+// expired login challenges, abandoned single sign-on logins and failed login
+// counters nobody has added to for a day
+func purgeAuthData(db *gorm.DB) {
+	now := time.Now()
+	if err := repository.DeleteStalePasswordResets(db, now.Add(-staleResetAge)); err != nil {
+		log.Printf("Error purging password resets: %v", err)
+	}
+	if err := repository.DeleteExpiredLoginChallenges(db, now); err != nil {
+		log.Printf("Error purging login challenges: %v", err)
+	}
+	if err := repository.DeleteExpiredOIDCLoginStates(db, now); err != nil {
+		log.Printf("Error purging single sign-on logins: %v", err)
+	}
+	if err := repository.DeleteStaleLoginThrottles(db, now.Add(-staleResetAge)); err != nil {
+		log.Printf("Error purging login throttles: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/middleware/audit.go
+
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+)
+
+// AuditContext attaches the client's IP address and user agent to the request
+// context so that changes made while handling the request can be attributed.
+// AuthMiddleware adds the authenticated user to the same actor.
+func AuditContext(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		setActor(c, audit.Actor{
+			IPAddress: c.RealIP(),
+			UserAgent: c.Request().UserAgent(),
+		})
+		return next(c)
+	}
+}
+
+func setActor(c echo.Context, actor audit.Actor) {
+	req := c.Request()
+	c.SetRequest(req.WithContext(audit.WithActor(req.Context(), actor)))
+}
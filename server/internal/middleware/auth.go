@@ -19,42 +19,257 @@
 package middleware
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
-	"github.com/tiny-giraffes/life-beacon-360/server/config"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		// Get authorization header
-		authHeader := c.Request().Header.Get("Authorization")
+// Context keys under which AuthMiddleware stores the authenticated caller
+const (
+	UserContextKey    = "user"
+	SessionContextKey = "session"
+	APIKeyContextKey  = "api_key"
+)
+
+// AuthMiddleware resolves the session token of the request to a user and
+// stores both the user and the session in the Echo context.
+//
+// API keys are accepted instead of a session only when scopes are given and
+// the key carries one of them; the key is then stored instead of a session.
+func AuthMiddleware(db *gorm.DB, scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, viaCookie := extractToken(c)
+			if token == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized - Missing authentication token",
+				})
+			}
+
+			// Clients guessing tokens are locked out by IP address just like
+			// clients guessing passwords
+			now := time.Now()
+			ipKey := auth.IPThrottleKey(c.RealIP())
+			throttles, err := repository.GetLoginThrottles(db, ipKey)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to validate session",
+				})
+			}
+			if len(throttles) > 0 && throttles[0].IsLocked(now) {
+				seconds := int(math.Ceil(throttles[0].LockedUntil.Sub(now).Seconds()))
+				c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+				return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+					"error":       "Too many failed attempts, please try again later",
+					"retry_after": seconds,
+				})
+			}
+
+			if !viaCookie && auth.IsAPIKey(token) {
+				return authenticateAPIKey(c, db, next, token, ipKey, now, scopes)
+			}
+
+			// Resolve token to an active session; only its hash is stored
+			tokenHash := auth.HashToken(token)
+			session, err := repository.GetActiveSession(db, tokenHash)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					recordUnknownToken(c, db, tokenHash, ipKey)
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": "Unauthorized - Invalid authentication token",
+					})
+				}
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to validate session",
+				})
+			}
+
+			if !session.User.IsActive {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized - Account is deactivated",
+				})
+			}
+
+			// Expired sessions are deactivated so they are not looked at again
+			expired, err := auth.IsSessionExpired(db, session, now)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to validate session",
+				})
+			}
+			if expired {
+				if err := repository.DeactivateSession(db, session.TokenHash); err != nil {
+					log.Printf("Error deactivating expired session: %v", err)
+				}
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized - Session expired",
+				})
+			}
+
+			// Cookies are sent by the browser automatically, so state-changing
+			// requests must prove they came from our own web client
+			if viaCookie && auth.IsStateChangingMethod(c.Request().Method) {
+				if !auth.ValidCSRFToken(session.CSRFToken, c.Request().Header.Get(auth.CSRFHeaderName)) {
+					return c.JSON(http.StatusForbidden, map[string]string{
+						"error": "Forbidden - Invalid CSRF token",
+					})
+				}
+			}
+
+			// Record activity; a failure here should not block the request
+			if err := repository.TouchSession(db, session.TokenHash, now); err != nil {
+				log.Printf("Error updating session activity: %v", err)
+			}
+
+			c.Set(UserContextKey, &session.User)
+			c.Set(SessionContextKey, session)
+			setActor(c, audit.Actor{
+				UserID:    &session.UserID,
+				IPAddress: c.RealIP(),
+				UserAgent: c.Request().UserAgent(),
+			})
+
+			return next(c)
+		}
+	}
+}
 
-		// Check if header is empty
-		if authHeader == "" {
+// authenticateAPIKey resolves an API key to its user and continues with next
+// if the key carries one of the scopes the route accepts
+func authenticateAPIKey(c echo.Context, db *gorm.DB, next echo.HandlerFunc, token, ipKey string, now time.Time, scopes []string) error {
+	key, err := repository.GetAPIKeyByHash(db, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			recordIPFailure(c, db, ipKey)
 			return c.JSON(http.StatusUnauthorized, map[string]string{
-				"error": "Unauthorized - Missing authentication token",
+				"error": "Unauthorized - Invalid API key",
 			})
 		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to validate API key",
+		})
+	}
 
-		// Extract token - supports both "Bearer TOKEN" and plain "TOKEN" formats
-		var token string
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			// Extract token from "Bearer TOKEN" format
-			token = strings.TrimPrefix(authHeader, "Bearer ")
-		} else {
-			// Use the raw header value as token
-			token = authHeader
+	if !key.IsUsable(now) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized - API key is revoked or expired",
+		})
+	}
+	if !key.User.IsActive {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized - Account is deactivated",
+		})
+	}
+
+	allowed := false
+	for _, scope := range scopes {
+		if key.HasScope(scope) {
+			allowed = true
+			break
 		}
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Forbidden - API key lacks the required scope",
+		})
+	}
 
-		// Validate token
-		if token == "" || token != config.AppConfig.ApiToken {
-			return c.JSON(http.StatusUnauthorized, map[string]string{
-				"error": "Unauthorized - Invalid authentication token",
+	// Record usage; a failure here should not block the request
+	if err := repository.TouchAPIKey(db, key.ID, now, c.RealIP()); err != nil {
+		log.Printf("Error updating API key usage: %v", err)
+	}
+
+	c.Set(UserContextKey, &key.User)
+	c.Set(APIKeyContextKey, key)
+	setActor(c, audit.Actor{
+		UserID:    &key.UserID,
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	})
+
+	return next(c)
+}
+
+// recordUnknownToken counts a token that never belonged to any session as a
+// failed attempt of the client IP. Tokens of ended sessions are still sent by
+// clients that were logged out, so they do not count.
+func recordUnknownToken(c echo.Context, db *gorm.DB, tokenHash, ipKey string) {
+	exists, err := repository.SessionExists(db, tokenHash)
+	if err != nil || exists {
+		return
+	}
+	recordIPFailure(c, db, ipKey)
+}
+
+// recordIPFailure counts a failed attempt against the client IP. Errors are
+// only logged, the request is rejected anyway.
+func recordIPFailure(c echo.Context, db *gorm.DB, ipKey string) {
+	policy, err := auth.LoadLockoutPolicy(db)
+	if err == nil {
+		_, err = repository.RecordLoginFailure(db.WithContext(c.Request().Context()), ipKey, policy.IPThreshold, policy.Duration, nil)
+	}
+	if err != nil {
+		log.Printf("Error recording failed attempt for %s: %v", ipKey, err)
+	}
+}
+
+// extractToken returns the session token from the Authorization header used by
+// mobile and desktop clients, falling back to the session cookie of web clients
+func extractToken(c echo.Context) (token string, viaCookie bool) {
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
+		// Supports both "Bearer TOKEN" and plain "TOKEN" formats
+		return strings.TrimPrefix(authHeader, "Bearer "), false
+	}
+
+	cookie, err := c.Cookie(auth.SessionCookieName)
+	if err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// RequireAdmin rejects requests from authenticated users without the admin role.
+// It must be chained after AuthMiddleware.
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := CurrentUser(c)
+		if user == nil || !user.IsAdmin() {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Forbidden - Admin role required",
 			})
 		}
 
 		return next(c)
 	}
 }
+
+// CurrentUser returns the authenticated user stored by AuthMiddleware
+func CurrentUser(c echo.Context) *models.User {
+	user, _ := c.Get(UserContextKey).(*models.User)
+	return user
+}
+
+// CurrentSession returns the session stored by AuthMiddleware, or nil for
+// requests made with an API key
+func CurrentSession(c echo.Context) *models.Session {
+	session, _ := c.Get(SessionContextKey).(*models.Session)
+	return session
+}
+
+// CurrentAPIKey returns the API key stored by AuthMiddleware, or nil for
+// requests made with a session
+func CurrentAPIKey(c echo.Context) *models.APIKey {
+	key, _ := c.Get(APIKeyContextKey).(*models.APIKey)
+	return key
+}
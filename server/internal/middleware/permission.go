@@ -0,0 +1,114 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/middleware/permission.go
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/auth"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// TargetUserContextKey is where RequirePermissionOn stores the target user
+const TargetUserContextKey = "target_user"
+
+// RequirePermission rejects requests from users that do not hold the given
+// permission for any target. It must be chained after AuthMiddleware.
+func RequirePermission(db *gorm.DB, permissionType string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			allowed, err := auth.HasPermission(db, CurrentUser(c), permissionType, nil)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to check permissions",
+				})
+			}
+			if !allowed {
+				return forbidden(c, permissionType)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequirePermissionOn rejects requests from users that do not hold the given
+// permission for the user identified by a path parameter ("me" refers to the
+// caller). The target user is stored in the context for the handler.
+// It must be chained after AuthMiddleware.
+func RequirePermissionOn(db *gorm.DB, permissionType string, param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := CurrentUser(c)
+
+			target := user
+			if value := c.Param(param); value != "me" {
+				targetID, err := uuid.Parse(value)
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, map[string]string{
+						"error": "Invalid user ID",
+					})
+				}
+
+				target, err = repository.GetUserByID(db, targetID)
+				if err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return c.JSON(http.StatusNotFound, map[string]string{
+							"error": "User not found",
+						})
+					}
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to retrieve user",
+					})
+				}
+			}
+
+			allowed, err := auth.HasPermission(db, user, permissionType, target)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to check permissions",
+				})
+			}
+			if !allowed {
+				return forbidden(c, permissionType)
+			}
+
+			c.Set(TargetUserContextKey, target)
+			return next(c)
+		}
+	}
+}
+
+// TargetUser returns the target user stored by RequirePermissionOn
+func TargetUser(c echo.Context) *models.User {
+	user, _ := c.Get(TargetUserContextKey).(*models.User)
+	return user
+}
+
+func forbidden(c echo.Context, permissionType string) error {
+	return c.JSON(http.StatusForbidden, map[string]string{
+		"error":      "Forbidden - Missing permission",
+		"permission": permissionType,
+	})
+}
@@ -0,0 +1,48 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddSetupStateTableMigration adds the table that locks first-run setup
+type AddSetupStateTableMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddSetupStateTableMigration) ID() string {
+	return "004_add_setup_state_table"
+}
+
+// Up creates the setup_states table
+func (m *AddSetupStateTableMigration) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SetupState{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Down removes the setup_states table
+func (m *AddSetupStateTableMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.SetupState{}); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddSessionRevocationMigration adds a public ID and revocation details to sessions
+type AddSessionRevocationMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddSessionRevocationMigration) ID() string {
+	return "005_add_session_revocation"
+}
+
+// Up adds the id, revoked_at and revoked_by columns to the sessions table
+func (m *AddSessionRevocationMigration) Up(db *gorm.DB) error {
+	// The default fills in an ID for every existing session
+	if !db.Migrator().HasColumn(&models.Session{}, "ID") {
+		if err := db.Migrator().AddColumn(&models.Session{}, "ID"); err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasIndex(&models.Session{}, "ID") {
+		if err := db.Migrator().CreateIndex(&models.Session{}, "ID"); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range []string{"RevokedAt", "RevokedBy"} {
+		if db.Migrator().HasColumn(&models.Session{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.Session{}, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Down removes the columns added by Up
+func (m *AddSessionRevocationMigration) Down(db *gorm.DB) error {
+	for _, field := range []string{"RevokedBy", "RevokedAt", "ID"} {
+		if err := db.Migrator().DropColumn(&models.Session{}, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddSessionCSRFTokenMigration adds the CSRF token used by cookie-based web sessions
+type AddSessionCSRFTokenMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddSessionCSRFTokenMigration) ID() string {
+	return "006_add_session_csrf_token"
+}
+
+// Up adds the csrf_token column to the sessions table
+func (m *AddSessionCSRFTokenMigration) Up(db *gorm.DB) error {
+	if db.Migrator().HasColumn(&models.Session{}, "CSRFToken") {
+		return nil
+	}
+
+	return db.Migrator().AddColumn(&models.Session{}, "CSRFToken")
+}
+
+// Down removes the csrf_token column from the sessions table
+func (m *AddSessionCSRFTokenMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropColumn(&models.Session{}, "CSRFToken")
+}
@@ -0,0 +1,50 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddPermissionsTableMigration adds the permissions table
+type AddPermissionsTableMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddPermissionsTableMigration) ID() string {
+	return "007_add_permissions_table"
+}
+
+// Up creates the permissions table
+func (m *AddPermissionsTableMigration) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Permission{}); err != nil {
+		return err
+	}
+
+	// A grant belongs to exactly one user or one group
+	return db.Exec(`ALTER TABLE permissions ADD CONSTRAINT chk_permissions_subject
+		CHECK ((user_id IS NULL) <> (group_id IS NULL))`).Error
+}
+
+// Down removes the permissions table
+func (m *AddPermissionsTableMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.Permission{}); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// RenameMemberRoleMigration renames the 'member' role to 'user'
+type RenameMemberRoleMigration struct{}
+
+// ID returns the migration identifier
+func (m *RenameMemberRoleMigration) ID() string {
+	return "008_rename_member_role"
+}
+
+// Up switches existing members and the column default to the 'user' role
+func (m *RenameMemberRoleMigration) Up(db *gorm.DB) error {
+	if err := db.Exec("ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user'").Error; err != nil {
+		return err
+	}
+
+	return db.Exec("UPDATE users SET role = 'user' WHERE role = 'member'").Error
+}
+
+// Down restores the 'member' role
+func (m *RenameMemberRoleMigration) Down(db *gorm.DB) error {
+	if err := db.Exec("ALTER TABLE users ALTER COLUMN role SET DEFAULT 'member'").Error; err != nil {
+		return err
+	}
+
+	return db.Exec("UPDATE users SET role = 'member' WHERE role = 'user'").Error
+}
@@ -0,0 +1,48 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddAuditLogsTableMigration adds the audit_logs table
+type AddAuditLogsTableMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddAuditLogsTableMigration) ID() string {
+	return "009_add_audit_logs_table"
+}
+
+// Up creates the audit_logs table
+func (m *AddAuditLogsTableMigration) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.AuditLog{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Down removes the audit_logs table
+func (m *AddAuditLogsTableMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.AuditLog{}); err != nil {
+		return err
+	}
+
+	return nil
+}
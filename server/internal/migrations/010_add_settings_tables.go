@@ -0,0 +1,57 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddSettingsTablesMigration adds the global, group and user settings tables
+type AddSettingsTablesMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddSettingsTablesMigration) ID() string {
+	return "010_add_settings_tables"
+}
+
+// Up creates the settings tables and the single global settings row
+func (m *AddSettingsTablesMigration) Up(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.GlobalSettings{}, &models.GroupSettings{}, &models.UserSettings{}); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&models.GlobalSettings{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	// Column defaults provide the initial values
+	return db.Create(&models.GlobalSettings{}).Error
+}
+
+// Down removes the settings tables
+func (m *AddSettingsTablesMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.UserSettings{}, &models.GroupSettings{}, &models.GlobalSettings{}); err != nil {
+		return err
+	}
+
+	return nil
+}
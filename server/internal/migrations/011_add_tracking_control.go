@@ -0,0 +1,61 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddTrackingControlMigration adds the server-side tracking state and the
+// tracking control log
+type AddTrackingControlMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddTrackingControlMigration) ID() string {
+	return "011_add_tracking_control"
+}
+
+// Up adds the tracking state columns to user_settings and creates the
+// tracking_control_logs table
+func (m *AddTrackingControlMigration) Up(db *gorm.DB) error {
+	for _, field := range []string{"TrackingEnabled", "TrackingChangedBy", "TrackingChangedAt"} {
+		if db.Migrator().HasColumn(&models.UserSettings{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.UserSettings{}, field); err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(&models.TrackingControlLog{})
+}
+
+// Down removes the tracking control log and the tracking state columns
+func (m *AddTrackingControlMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.TrackingControlLog{}); err != nil {
+		return err
+	}
+
+	for _, field := range []string{"TrackingChangedAt", "TrackingChangedBy", "TrackingEnabled"} {
+		if err := db.Migrator().DropColumn(&models.UserSettings{}, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
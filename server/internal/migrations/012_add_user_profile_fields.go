@@ -0,0 +1,59 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddUserProfileFieldsMigration adds profile and account state fields to users
+type AddUserProfileFieldsMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddUserProfileFieldsMigration) ID() string {
+	return "012_add_user_profile_fields"
+}
+
+// userProfileFields are the fields added by this migration
+var userProfileFields = []string{"FullName", "Phone", "JobTitle", "IsActive", "FirstLogin"}
+
+// Up adds the full_name, phone, job_title, is_active and first_login columns
+// to the users table
+func (m *AddUserProfileFieldsMigration) Up(db *gorm.DB) error {
+	for _, field := range userProfileFields {
+		if db.Migrator().HasColumn(&models.User{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Down removes the profile and account state columns from the users table
+func (m *AddUserProfileFieldsMigration) Down(db *gorm.DB) error {
+	for _, field := range userProfileFields {
+		if err := db.Migrator().DropColumn(&models.User{}, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
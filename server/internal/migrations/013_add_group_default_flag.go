@@ -0,0 +1,59 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddGroupDefaultFlagMigration marks the default group that new and
+// provisioned users land in and that can never be deleted
+type AddGroupDefaultFlagMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddGroupDefaultFlagMigration) ID() string {
+	return "013_add_group_default_flag"
+}
+
+// Up adds the is_default column, marks the oldest "Main" group as default and
+// makes sure there can only ever be one default group
+func (m *AddGroupDefaultFlagMigration) Up(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Group{}, "IsDefault") {
+		if err := db.Migrator().AddColumn(&models.Group{}, "IsDefault"); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Exec(`
+		UPDATE groups SET is_default = true
+		WHERE id = (SELECT id FROM groups WHERE name = ? ORDER BY created_at LIMIT 1)
+		AND NOT EXISTS (SELECT 1 FROM groups WHERE is_default)`, models.DefaultGroupName).Error; err != nil {
+		return err
+	}
+
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_single_default ON groups (is_default) WHERE is_default").Error
+}
+
+// Down removes the is_default column and its index
+func (m *AddGroupDefaultFlagMigration) Down(db *gorm.DB) error {
+	if err := db.Exec("DROP INDEX IF EXISTS idx_groups_single_default").Error; err != nil {
+		return err
+	}
+
+	return db.Migrator().DropColumn(&models.Group{}, "IsDefault")
+}
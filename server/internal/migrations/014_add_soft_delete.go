@@ -0,0 +1,60 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddSoftDeleteMigration adds deleted_at to users and groups so they can be
+// deleted without losing their history right away
+type AddSoftDeleteMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddSoftDeleteMigration) ID() string {
+	return "014_add_soft_delete"
+}
+
+// Up adds the indexed deleted_at column to the users and groups tables
+func (m *AddSoftDeleteMigration) Up(db *gorm.DB) error {
+	for _, model := range []interface{}{&models.User{}, &models.Group{}} {
+		if !db.Migrator().HasColumn(model, "DeletedAt") {
+			if err := db.Migrator().AddColumn(model, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+		if !db.Migrator().HasIndex(model, "DeletedAt") {
+			if err := db.Migrator().CreateIndex(model, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Down removes the deleted_at column from the users and groups tables
+func (m *AddSoftDeleteMigration) Down(db *gorm.DB) error {
+	for _, model := range []interface{}{&models.User{}, &models.Group{}} {
+		if err := db.Migrator().DropColumn(model, "DeletedAt"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddPasswordResetTablesMigration adds the password reset token and request tables
+type AddPasswordResetTablesMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddPasswordResetTablesMigration) ID() string {
+	return "016_add_password_reset_tables"
+}
+
+// Up creates the password_reset_tokens and password_reset_requests tables
+func (m *AddPasswordResetTablesMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.PasswordResetToken{}, &models.PasswordResetRequest{})
+}
+
+// Down removes the password reset tables
+func (m *AddPasswordResetTablesMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.PasswordResetRequest{}, &models.PasswordResetToken{})
+}
@@ -0,0 +1,63 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddTwoFactorAuthMigration adds TOTP secrets, recovery codes, login
+// challenges and the setting that requires two-factor authentication for admins
+type AddTwoFactorAuthMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddTwoFactorAuthMigration) ID() string {
+	return "017_add_two_factor_auth"
+}
+
+// Up adds the two_factor_enabled column to users, the require_2fa_for_admins
+// column to global_settings and creates the two_factor_secrets,
+// recovery_codes and login_challenges tables
+func (m *AddTwoFactorAuthMigration) Up(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.User{}, "TwoFactorEnabled") {
+		if err := db.Migrator().AddColumn(&models.User{}, "TwoFactorEnabled"); err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasColumn(&models.GlobalSettings{}, "Require2FAForAdmins") {
+		if err := db.Migrator().AddColumn(&models.GlobalSettings{}, "Require2FAForAdmins"); err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(&models.TwoFactorSecret{}, &models.RecoveryCode{}, &models.LoginChallenge{})
+}
+
+// Down drops the two-factor tables and columns
+func (m *AddTwoFactorAuthMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.LoginChallenge{}, &models.RecoveryCode{}, &models.TwoFactorSecret{}); err != nil {
+		return err
+	}
+
+	if err := db.Migrator().DropColumn(&models.GlobalSettings{}, "Require2FAForAdmins"); err != nil {
+		return err
+	}
+
+	return db.Migrator().DropColumn(&models.User{}, "TwoFactorEnabled")
+}
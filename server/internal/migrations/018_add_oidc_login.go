@@ -0,0 +1,58 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddOIDCLoginMigration links users to single sign-on identities and stores
+// pending single sign-on logins
+type AddOIDCLoginMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddOIDCLoginMigration) ID() string {
+	return "018_add_oidc_login"
+}
+
+// Up adds the uniquely indexed oidc_subject column to users and creates the
+// oidc_login_states table
+func (m *AddOIDCLoginMigration) Up(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.User{}, "OIDCSubject") {
+		if err := db.Migrator().AddColumn(&models.User{}, "OIDCSubject"); err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasIndex(&models.User{}, "OIDCSubject") {
+		if err := db.Migrator().CreateIndex(&models.User{}, "OIDCSubject"); err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(&models.OIDCLoginState{})
+}
+
+// Down drops the oidc_login_states table and the oidc_subject column
+func (m *AddOIDCLoginMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.OIDCLoginState{}); err != nil {
+		return err
+	}
+
+	return db.Migrator().DropColumn(&models.User{}, "OIDCSubject")
+}
@@ -0,0 +1,63 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddLoginThrottlesMigration adds failed login tracking and the lockout settings
+type AddLoginThrottlesMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddLoginThrottlesMigration) ID() string {
+	return "019_add_login_throttles"
+}
+
+// lockoutSettingFields are the global settings added by this migration
+var lockoutSettingFields = []string{"LockoutThreshold", "LockoutDurationMinutes", "IPLockoutThreshold"}
+
+// Up adds the lockout columns to global_settings and creates the
+// login_throttles table
+func (m *AddLoginThrottlesMigration) Up(db *gorm.DB) error {
+	for _, field := range lockoutSettingFields {
+		if db.Migrator().HasColumn(&models.GlobalSettings{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.GlobalSettings{}, field); err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(&models.LoginThrottle{})
+}
+
+// Down drops the login_throttles table and the lockout settings
+func (m *AddLoginThrottlesMigration) Down(db *gorm.DB) error {
+	if err := db.Migrator().DropTable(&models.LoginThrottle{}); err != nil {
+		return err
+	}
+
+	for _, field := range lockoutSettingFields {
+		if err := db.Migrator().DropColumn(&models.GlobalSettings{}, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
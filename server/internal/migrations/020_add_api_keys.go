@@ -0,0 +1,40 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddAPIKeysMigration adds scoped API keys for headless devices
+type AddAPIKeysMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddAPIKeysMigration) ID() string {
+	return "020_add_api_keys"
+}
+
+// Up creates the api_keys table
+func (m *AddAPIKeysMigration) Up(db *gorm.DB) error {
+	return db.AutoMigrate(&models.APIKey{})
+}
+
+// Down drops the api_keys table
+func (m *AddAPIKeysMigration) Down(db *gorm.DB) error {
+	return db.Migrator().DropTable(&models.APIKey{})
+}
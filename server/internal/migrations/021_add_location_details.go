@@ -0,0 +1,80 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddLocationDetailsMigration adds accuracy, motion, battery and device
+// details to locations and separates the device time from the receive time
+type AddLocationDetailsMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddLocationDetailsMigration) ID() string {
+	return "021_add_location_details"
+}
+
+// locationDetailFields are the nullable location columns added by this migration
+var locationDetailFields = []string{"Accuracy", "Altitude", "Speed", "Bearing", "BatteryLevel", "ClientID", "ClientType"}
+
+// Up renames created_at to received_at, adds the detail columns and backfills
+// recorded_at of existing points with their receive time
+func (m *AddLocationDetailsMigration) Up(db *gorm.DB) error {
+	if db.Migrator().HasColumn(&models.Location{}, "created_at") {
+		if err := db.Migrator().RenameColumn(&models.Location{}, "created_at", "received_at"); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range locationDetailFields {
+		if db.Migrator().HasColumn(&models.Location{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.Location{}, field); err != nil {
+			return err
+		}
+	}
+
+	if !db.Migrator().HasColumn(&models.Location{}, "RecordedAt") {
+		if err := db.Exec("ALTER TABLE locations ADD COLUMN recorded_at timestamptz").Error; err != nil {
+			return err
+		}
+		if err := db.Exec("UPDATE locations SET recorded_at = received_at").Error; err != nil {
+			return err
+		}
+		if err := db.Exec("ALTER TABLE locations ALTER COLUMN recorded_at SET NOT NULL").Error; err != nil {
+			return err
+		}
+	}
+	if !db.Migrator().HasIndex(&models.Location{}, "RecordedAt") {
+		return db.Migrator().CreateIndex(&models.Location{}, "RecordedAt")
+	}
+	return nil
+}
+
+// Down drops the detail columns and renames received_at back to created_at
+func (m *AddLocationDetailsMigration) Down(db *gorm.DB) error {
+	for _, field := range append(locationDetailFields, "RecordedAt") {
+		if err := db.Migrator().DropColumn(&models.Location{}, field); err != nil {
+			return err
+		}
+	}
+
+	return db.Migrator().RenameColumn(&models.Location{}, "received_at", "created_at")
+}
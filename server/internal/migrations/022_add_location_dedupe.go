@@ -0,0 +1,76 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddLocationDedupeMigration keeps retried uploads from storing a location twice
+type AddLocationDedupeMigration struct{}
+
+// ID returns the migration identifier
+func (m *AddLocationDedupeMigration) ID() string {
+	return "022_add_location_dedupe"
+}
+
+// locationDedupeFields are the location columns added by this migration
+var locationDedupeFields = []string{"Sequence", "IdempotencyKey"}
+
+// locationDedupeIndexes are the partial unique indexes a duplicate location
+// would violate, by name
+var locationDedupeIndexes = map[string]string{
+	"idx_locations_idempotency_key":    "(user_id, idempotency_key) WHERE idempotency_key IS NOT NULL",
+	"idx_locations_client_recorded_at": "(user_id, client_id, recorded_at) WHERE client_id <> ''",
+	"idx_locations_client_sequence":    "(user_id, client_id, sequence) WHERE sequence IS NOT NULL",
+}
+
+// Up adds the sequence and idempotency_key columns and the unique indexes
+func (m *AddLocationDedupeMigration) Up(db *gorm.DB) error {
+	for _, field := range locationDedupeFields {
+		if db.Migrator().HasColumn(&models.Location{}, field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.Location{}, field); err != nil {
+			return err
+		}
+	}
+
+	for name, definition := range locationDedupeIndexes {
+		if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + name + " ON locations " + definition).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down drops the unique indexes and the columns
+func (m *AddLocationDedupeMigration) Down(db *gorm.DB) error {
+	for name := range locationDedupeIndexes {
+		if err := db.Exec("DROP INDEX IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, field := range locationDedupeFields {
+		if err := db.Migrator().DropColumn(&models.Location{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
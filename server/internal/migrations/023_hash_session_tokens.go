@@ -0,0 +1,55 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migrations
+
+import (
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// HashSessionTokensMigration stops storing session tokens in plain text
+type HashSessionTokensMigration struct{}
+
+// ID returns the migration identifier
+func (m *HashSessionTokensMigration) ID() string {
+	return "023_hash_session_tokens"
+}
+
+// Up renames the token column to token_hash. Existing sessions are ended and
+// their plain text tokens replaced by their hashes, so everyone has to log in
+// again.
+func (m *HashSessionTokensMigration) Up(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Session{}, "token") {
+		return nil
+	}
+
+	if err := db.Exec(`
+		UPDATE sessions SET
+			token = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+			is_active = false,
+			revoked_at = COALESCE(revoked_at, now())`).Error; err != nil {
+		return err
+	}
+
+	return db.Migrator().RenameColumn(&models.Session{}, "token", "token_hash")
+}
+
+// Down renames token_hash back to token. Sessions stay ended, their tokens
+// cannot be recovered from the hashes.
+func (m *HashSessionTokensMigration) Down(db *gorm.DB) error {
+	return db.Migrator().RenameColumn(&models.Session{}, "token_hash", "token")
+}
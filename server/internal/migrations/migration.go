@@ -100,5 +100,26 @@ func getAllMigrations() []Migration {
 	return []Migration{
 		&InitialSchemaMigration{},
 		&AddUserGroupTablesMigration{},
+		&AddSessionsTableMigration{},
+		&AddSetupStateTableMigration{},
+		&AddSessionRevocationMigration{},
+		&AddSessionCSRFTokenMigration{},
+		&AddPermissionsTableMigration{},
+		&RenameMemberRoleMigration{},
+		&AddAuditLogsTableMigration{},
+		&AddSettingsTablesMigration{},
+		&AddTrackingControlMigration{},
+		&AddUserProfileFieldsMigration{},
+		&AddGroupDefaultFlagMigration{},
+		&AddSoftDeleteMigration{},
+		&AddInvitationsTableMigration{},
+		&AddPasswordResetTablesMigration{},
+		&AddTwoFactorAuthMigration{},
+		&AddOIDCLoginMigration{},
+		&AddLoginThrottlesMigration{},
+		&AddAPIKeysMigration{},
+		&AddLocationDetailsMigration{},
+		&AddLocationDedupeMigration{},
+		&HashSessionTokensMigration{},
 	}
 }
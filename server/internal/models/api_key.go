@@ -0,0 +1,142 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scopes an API key can carry
+const (
+	ScopeLocationsWrite    = "locations:write"     // post locations for the key's user
+	ScopeLocationsReadSelf = "locations:read:self" // read the key's user's own locations
+)
+
+// APIKeyScopes lists every known API key scope
+var APIKeyScopes = []string{
+	ScopeLocationsWrite,
+	ScopeLocationsReadSelf,
+}
+
+// APIKeyTokenPrefix starts every API key so it can be told apart from session
+// tokens without a database lookup
+const APIKeyTokenPrefix = "lb360_"
+
+// StringList is a list of strings stored as a JSON array
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	return fmt.Errorf("cannot scan %T into StringList", value)
+}
+
+// Contains checks if the list contains the given string
+func (l StringList) Contains(s string) bool {
+	for _, item := range l {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey lets a headless device act for a single user within its scopes. Only
+// the hash of the key is stored; the prefix identifies the key in listings.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(20);not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scopes     StringList `gorm:"type:jsonb;default:'[]';not null" json:"scopes"`
+	ExpiresAt  *time.Time `gorm:"type:timestamptz" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `gorm:"type:timestamptz" json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"column:last_used_ip;type:varchar(45)" json:"last_used_ip,omitempty"`
+	CreatedBy  uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	RevokedAt  *time.Time `gorm:"type:timestamptz" json:"revoked_at,omitempty"`
+	RevokedBy  *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// CreateAPIKeyRequest is the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is the created key together with the only copy of its
+// plain text value
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUsable checks if the key is neither revoked nor expired
+func (k *APIKey) IsUsable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// HasScope checks if the key carries the given scope
+func (k *APIKey) HasScope(scope string) bool {
+	return k.Scopes.Contains(scope)
+}
+
+// IsValidAPIKeyScope checks if the given value is a known API key scope
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -23,20 +23,40 @@ import (
 	"gorm.io/gorm"
 )
 
+// DefaultGroupName is the name of the group created during first-run setup
+const DefaultGroupName = "Main"
+
 type Group struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
-	CreatedAt time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
-	UpdatedAt time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
-	
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
+	IsDefault bool           `gorm:"default:false;not null" json:"is_default"` // the "Main" group; cannot be deleted
+	CreatedAt time.Time      `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"type:timestamptz;index" json:"deleted_at"`
+
 	// Relations
 	Users []User `gorm:"foreignKey:GroupID" json:"users,omitempty"`
 }
 
+// GroupRequest is the request body for creating or renaming a group
+type GroupRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// MoveUsersRequest is the request body for moving users into a group
+type MoveUsersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" validate:"required"`
+}
+
+// MoveUsersResponse reports how many users changed group
+type MoveUsersResponse struct {
+	Moved int64 `json:"moved"`
+}
+
 // BeforeCreate will set a UUID rather than numeric ID
 func (g *Group) BeforeCreate(tx *gorm.DB) error {
 	if g.ID == uuid.Nil {
 		g.ID = uuid.New()
 	}
 	return nil
-}
\ No newline at end of file
+}
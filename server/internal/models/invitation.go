@@ -0,0 +1,71 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invitation lets a newly created user set their own password. Only the hash
+// of the token sent by email is stored.
+type Invitation struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash  string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	AcceptedAt *time.Time `gorm:"type:timestamptz" json:"accepted_at,omitempty"`
+	CreatedBy  uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// AcceptInviteRequest is the request body for accepting an invitation
+type AcceptInviteRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// InvitationResponse reports the outcome of sending an invitation
+type InvitationResponse struct {
+	InvitationSent      bool      `json:"invitation_sent"`
+	InvitationExpiresAt time.Time `json:"invitation_expires_at"`
+	InvitationError     string    `json:"invitation_error,omitempty"` // why the email was not sent
+}
+
+// CreateUserResponse is the created user together with the invitation outcome
+type CreateUserResponse struct {
+	User
+	InvitationResponse
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (i *Invitation) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsPending checks if the invitation can still be accepted
+func (i *Invitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
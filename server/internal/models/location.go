@@ -22,18 +22,69 @@ import (
 	"github.com/google/uuid"
 )
 
+// Location is a single position fix. RecordedAt is when the device took the
+// fix, ReceivedAt when the server stored it; they differ for points a device
+// queued while offline.
 type Location struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid" json:"user_id"`
-	Latitude  float64   `gorm:"type:float8;not null" json:"latitude"`
-	Longitude float64   `gorm:"type:float8;not null" json:"longitude"`
-	CreatedAt time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"createdAt"`
-	
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	Latitude       float64   `gorm:"type:float8;not null" json:"latitude"`
+	Longitude      float64   `gorm:"type:float8;not null" json:"longitude"`
+	Accuracy       *float64  `gorm:"type:float8" json:"accuracy,omitempty"`        // horizontal accuracy in meters
+	Altitude       *float64  `gorm:"type:float8" json:"altitude,omitempty"`        // meters above the WGS 84 ellipsoid
+	Speed          *float64  `gorm:"type:float8" json:"speed,omitempty"`           // meters per second
+	Bearing        *float64  `gorm:"type:float8" json:"bearing,omitempty"`         // degrees clockwise from true north
+	BatteryLevel   *int      `gorm:"type:smallint" json:"battery_level,omitempty"` // percent
+	RecordedAt     time.Time `gorm:"type:timestamptz;not null;index" json:"recorded_at"`
+	ReceivedAt     time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"received_at"`
+	ClientID       string    `gorm:"type:varchar(100)" json:"client_id,omitempty"`
+	ClientType     string    `gorm:"type:varchar(20)" json:"client_type,omitempty"` // 'web', 'mobile' or 'desktop'
+	Sequence       *int64    `gorm:"type:bigint" json:"sequence,omitempty"`         // client-side sequence number, unique per client
+	IdempotencyKey *string   `gorm:"type:varchar(255)" json:"-"`                    // Idempotency-Key header of the request that stored it
+
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 type LocationRequest struct {
-	Latitude  float64 `json:"latitude" validate:"required"`
-	Longitude float64 `json:"longitude" validate:"required"`
+	Latitude     *float64   `json:"latitude" validate:"required"` // pointers tell 0 from missing
+	Longitude    *float64   `json:"longitude" validate:"required"`
+	Accuracy     *float64   `json:"accuracy"`
+	Altitude     *float64   `json:"altitude"`
+	Speed        *float64   `json:"speed"`
+	Bearing      *float64   `json:"bearing"`
+	BatteryLevel *int       `json:"battery_level"`
+	RecordedAt   *time.Time `json:"recorded_at"` // device time; defaults to the time the server receives the point
+	ClientID     string     `json:"client_id"`
+	ClientType   string     `json:"client_type"`
+	Sequence     *int64     `json:"sequence"`
+}
+
+// Outcomes of a single point in a batch upload
+const (
+	LocationResultCreated   = "created"   // stored; drop it from the queue
+	LocationResultDuplicate = "duplicate" // stored by an earlier upload; drop it from the queue
+	LocationResultRejected  = "rejected"  // invalid; drop it, retrying will not help
+)
+
+// LocationBatchRequest is the request body for uploading queued locations
+type LocationBatchRequest struct {
+	Locations []LocationRequest `json:"locations" validate:"required"`
+}
+
+// LocationBatchResult is the outcome of the point at Index in the batch
+type LocationBatchResult struct {
+	Index  int         `json:"index"`
+	Status string      `json:"status"` // 'created', 'duplicate' or 'rejected'
+	ID     uint        `json:"id,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Fields FieldErrors `json:"fields,omitempty"` // why a rejected point is invalid
+}
+
+// LocationBatchResponse reports the outcome of every point in a batch
+type LocationBatchResponse struct {
+	Created   int                   `json:"created"`
+	Duplicate int                   `json:"duplicate"`
+	Rejected  int                   `json:"rejected"`
+	Results   []LocationBatchResult `json:"results"`
 }
@@ -0,0 +1,41 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+)
+
+// Prefixes of login throttle keys
+const (
+	ThrottleKeyAccount = "account:" // followed by the lowercased username
+	ThrottleKeyIP      = "ip:"      // followed by the client IP address
+)
+
+// LoginThrottle counts recent failed logins for an account or an IP address
+// and records when they are locked out
+type LoginThrottle struct {
+	Key           string     `gorm:"type:varchar(300);primaryKey" json:"key"`
+	Failures      int        `gorm:"default:0;not null" json:"failures"`
+	LastFailureAt time.Time  `gorm:"type:timestamptz;not null" json:"last_failure_at"`
+	LockedUntil   *time.Time `gorm:"type:timestamptz" json:"locked_until,omitempty"`
+}
+
+// IsLocked checks if the key is locked out at the given time
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
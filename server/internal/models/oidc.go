@@ -0,0 +1,49 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OIDCLoginState remembers a single sign-on login between the redirect to
+// the identity provider and its callback
+type OIDCLoginState struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	StateHash    string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Nonce        string    `gorm:"type:varchar(64);not null" json:"-"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"type:timestamptz;not null" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+}
+
+// OIDCStatusResponse tells clients whether single sign-on is available
+type OIDCStatusResponse struct {
+	Enabled  bool   `json:"enabled"`
+	LoginURL string `json:"login_url,omitempty"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (s *OIDCLoginState) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
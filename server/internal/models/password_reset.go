@@ -0,0 +1,71 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken lets a user choose a new password once. Only the hash of
+// the token sent by email is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"type:timestamptz" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PasswordResetRequest records a reset request for an email address, whether
+// or not it belongs to a user, so requests can be rate limited per address
+type PasswordResetRequest struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Email     string    `gorm:"type:varchar(255);not null;index:idx_password_reset_requests_email_created" json:"email"`
+	IPAddress string    `gorm:"type:varchar(45)" json:"ip_address"`
+	CreatedAt time.Time `gorm:"type:timestamptz;default:current_timestamp;not null;index:idx_password_reset_requests_email_created" json:"created_at"`
+}
+
+// RequestPasswordResetRequest is the request body for asking for a reset email
+type RequestPasswordResetRequest struct {
+	Email string `json:"email" validate:"required"`
+}
+
+// ConfirmPasswordResetRequest is the request body for setting a new password
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUsable checks if the token can still be used
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,177 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permission types that can be granted to users or groups
+const (
+	PermissionControlOwnTracking = "can_control_own_tracking"
+	PermissionViewLocation       = "can_view_location"
+	PermissionControlTracking    = "can_control_tracking"
+	PermissionExportData         = "can_export_data"
+	PermissionViewReports        = "can_view_reports"
+	PermissionManageUsers        = "can_manage_users"
+	PermissionManageGroups       = "can_manage_groups"
+)
+
+// Targets a permission applies to
+const (
+	TargetSelf          = "self"
+	TargetGroup         = "group"
+	TargetSpecificUsers = "specific_users"
+	TargetAll           = "all"
+)
+
+// PermissionTypes lists every known permission type
+var PermissionTypes = []string{
+	PermissionControlOwnTracking,
+	PermissionViewLocation,
+	PermissionControlTracking,
+	PermissionExportData,
+	PermissionViewReports,
+	PermissionManageUsers,
+	PermissionManageGroups,
+}
+
+// UUIDList is a list of UUIDs stored as a JSON array
+type UUIDList []uuid.UUID
+
+// Value implements driver.Valuer
+func (l UUIDList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (l *UUIDList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	return fmt.Errorf("cannot scan %T into UUIDList", value)
+}
+
+// Contains checks if the list contains the given UUID
+func (l UUIDList) Contains(id uuid.UUID) bool {
+	for _, item := range l {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission grants a user, or every member of a group, the right to perform
+// an action on a set of target users. Exactly one of UserID and GroupID is set.
+type Permission struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	GroupID        *uuid.UUID `gorm:"type:uuid;index" json:"group_id,omitempty"`
+	PermissionType string     `gorm:"type:varchar(100);not null" json:"permission_type"`
+	TargetType     string     `gorm:"type:varchar(50);not null" json:"target_type"` // 'self', 'group', 'specific_users' or 'all'
+	TargetUsers    UUIDList   `gorm:"type:jsonb;default:'[]';not null" json:"target_users"`
+	GrantedBy      uuid.UUID  `gorm:"type:uuid;not null" json:"granted_by"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
+
+	// Relations
+	User  *User  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Group *Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (p *Permission) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// PermissionScope lists the users a permission covers
+type PermissionScope struct {
+	All      bool        // every user
+	GroupIDs []uuid.UUID // members of these groups
+	UserIDs  []uuid.UUID // these users
+}
+
+type GrantPermissionRequest struct {
+	UserID         *uuid.UUID  `json:"user_id"`
+	GroupID        *uuid.UUID  `json:"group_id"`
+	PermissionType string      `json:"permission_type" validate:"required"`
+	TargetType     string      `json:"target_type" validate:"required"`
+	TargetUsers    []uuid.UUID `json:"target_users"`
+}
+
+type ResetPermissionsResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// AppliesTo checks if the permission covers the target user on behalf of the
+// grantee. A nil target asks whether the permission is held at all.
+func (p *Permission) AppliesTo(grantee *User, target *User) bool {
+	if target == nil {
+		return true
+	}
+
+	switch p.TargetType {
+	case TargetAll:
+		return true
+	case TargetSelf:
+		return target.ID == grantee.ID
+	case TargetGroup:
+		return target.GroupID == grantee.GroupID
+	case TargetSpecificUsers:
+		return p.TargetUsers.Contains(target.ID)
+	}
+	return false
+}
+
+// IsValidPermissionType checks if the given value is a known permission type
+func IsValidPermissionType(permissionType string) bool {
+	for _, t := range PermissionTypes {
+		if t == permissionType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidTargetType checks if the given value is a known permission target
+func IsValidTargetType(targetType string) bool {
+	switch targetType {
+	case TargetSelf, TargetGroup, TargetSpecificUsers, TargetAll:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Client types a session or location can originate from
+const (
+	ClientTypeWeb     = "web"
+	ClientTypeMobile  = "mobile"
+	ClientTypeDesktop = "desktop"
+)
+
+type Session struct {
+	TokenHash    string     `gorm:"type:varchar(255);primaryKey" json:"-"`                              // only the hash of the token is stored
+	ID           uuid.UUID  `gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()" json:"id"` // public identifier, safe to expose
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CreatedAt    time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	LastActivity time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"last_activity"`
+	IPAddress    string     `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent    string     `gorm:"type:text" json:"user_agent"`
+	ClientType   string     `gorm:"type:varchar(20);not null" json:"client_type"` // 'web', 'mobile' or 'desktop'
+	IsActive     bool       `gorm:"default:true;not null" json:"is_active"`
+	RevokedAt    *time.Time `gorm:"type:timestamptz" json:"revoked_at,omitempty"`
+	RevokedBy    *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+	CSRFToken    string     `gorm:"column:csrf_token;type:varchar(64)" json:"-"` // only set for cookie-based web sessions
+
+	// Current marks the session used to make the request; not persisted
+	Current bool `gorm:"-" json:"current"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type LoginRequest struct {
+	Username   string `json:"username" validate:"required"`
+	Password   string `json:"password" validate:"required"`
+	ClientType string `json:"client_type"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type LoginResponse struct {
+	Token      string    `json:"token,omitempty"`      // omitted for web clients, which get a cookie instead
+	CSRFToken  string    `json:"csrf_token,omitempty"` // web clients only
+	ExpiresAt  time.Time `json:"expires_at"`
+	FirstLogin bool      `json:"first_login"` // the client must ask for a new password
+	User       User      `json:"user"`
+	// Set when two-factor authentication was enrolled during this login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// BeforeCreate will set a UUID if none was set
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// ExpiresAt returns the moment the session expires given the absolute maximum
+// duration and the inactivity window that each request extends
+func (s *Session) ExpiresAt(maxDuration, activityExtension time.Duration) time.Time {
+	absolute := s.CreatedAt.Add(maxDuration)
+	idle := s.LastActivity.Add(activityExtension)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+// GenerateToken returns a hex-encoded random token of n bytes
+func GenerateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// IsValidClientType checks if the given value is a known client type
+func IsValidClientType(clientType string) bool {
+	switch clientType {
+	case ClientTypeWeb, ClientTypeMobile, ClientTypeDesktop:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SettingsMap holds setting overrides keyed by setting name, stored as JSON
+type SettingsMap map[string]interface{}
+
+// Value implements driver.Valuer
+func (m SettingsMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (m *SettingsMap) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = SettingsMap{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	}
+	return fmt.Errorf("cannot scan %T into SettingsMap", value)
+}
+
+// GlobalSettings holds the system-wide defaults. The table has a single row.
+// JSON names double as the setting keys used for group and user overrides.
+type GlobalSettings struct {
+	ID                           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"-"`
+	TrackingInterval             int        `gorm:"default:300;not null" json:"tracking_interval"` // seconds
+	PollingInterval              int        `gorm:"default:60;not null" json:"polling_interval"`   // seconds
+	AccuracyMode                 string     `gorm:"type:varchar(20);default:'high';not null" json:"accuracy_mode"`
+	DataRetentionDays            *int       `json:"data_retention_days"` // nil keeps data forever
+	SessionMaxDurationDays       int        `gorm:"default:90;not null" json:"session_max_duration_days"`
+	SessionActivityExtensionDays int        `gorm:"default:14;not null" json:"session_activity_extension_days"`
+	BatteryLowThreshold          int        `gorm:"default:20;not null" json:"battery_low_threshold"` // percent, degrade accuracy below
+	BatteryStopThreshold         int        `gorm:"default:5;not null" json:"battery_stop_threshold"` // percent, stop tracking below
+	MandatoryTracking            bool       `gorm:"default:false;not null" json:"mandatory_tracking"`
+	Require2FAForAdmins          bool       `gorm:"column:require_2fa_for_admins;default:false;not null" json:"require_2fa_for_admins"`
+	LockoutThreshold             int        `gorm:"default:5;not null" json:"lockout_threshold"`                                 // failed logins per account before lockout
+	LockoutDurationMinutes       int        `gorm:"default:15;not null" json:"lockout_duration_minutes"`                         // also the window failures are counted in
+	IPLockoutThreshold           int        `gorm:"column:ip_lockout_threshold;default:50;not null" json:"ip_lockout_threshold"` // failed logins per IP address before lockout
+	LastModifiedBy               *uuid.UUID `gorm:"type:uuid" json:"-"`
+	CreatedAt                    time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"-"`
+	UpdatedAt                    time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"-"`
+}
+
+// GroupSettings overrides global settings for the members of a group
+type GroupSettings struct {
+	ID             uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GroupID        uuid.UUID   `gorm:"type:uuid;uniqueIndex;not null" json:"group_id"`
+	Settings       SettingsMap `gorm:"type:jsonb;default:'{}';not null" json:"settings"`
+	LastModifiedBy *uuid.UUID  `gorm:"type:uuid" json:"last_modified_by,omitempty"`
+	CreatedAt      time.Time   `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	UpdatedAt      time.Time   `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
+
+	// Relations
+	Group Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// UserSettings overrides group and global settings for a single user
+type UserSettings struct {
+	ID             uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID   `gorm:"type:uuid;uniqueIndex;not null" json:"user_id"`
+	Settings       SettingsMap `gorm:"type:jsonb;default:'{}';not null" json:"settings"`
+	LastModifiedBy *uuid.UUID  `gorm:"type:uuid" json:"last_modified_by,omitempty"`
+	LastModifiedAt *time.Time  `gorm:"type:timestamptz" json:"last_modified_at,omitempty"`
+	// Authoritative tracking state; clients follow it via the tracking config
+	TrackingEnabled   bool       `gorm:"default:true;not null" json:"tracking_enabled"`
+	TrackingChangedBy *uuid.UUID `gorm:"type:uuid" json:"tracking_changed_by,omitempty"`
+	TrackingChangedAt *time.Time `gorm:"type:timestamptz" json:"tracking_changed_at,omitempty"`
+	CreatedAt         time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (s *GlobalSettings) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (s *GroupSettings) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (s *UserSettings) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
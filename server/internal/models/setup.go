@@ -0,0 +1,51 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SetupStateID is the primary key of the single setup state row
+const SetupStateID = 1
+
+// SetupState records that first-run setup has been completed. The table holds
+// at most one row; its presence permanently locks the setup endpoint.
+type SetupState struct {
+	ID          int       `gorm:"primaryKey" json:"-"`
+	AdminID     uuid.UUID `gorm:"type:uuid;not null" json:"admin_id"`
+	CompletedAt time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"completed_at"`
+}
+
+type SetupRequest struct {
+	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	FullName string `json:"full_name" validate:"required"`
+	Phone    string `json:"phone" validate:"required"`
+}
+
+type SetupStatusResponse struct {
+	SetupRequired bool `json:"setup_required"`
+}
+
+type SetupResponse struct {
+	User  User  `json:"user"`
+	Group Group `json:"group"`
+}
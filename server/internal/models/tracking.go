@@ -0,0 +1,85 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TrackingConfig is the resolved tracking configuration clients poll for
+type TrackingConfig struct {
+	TrackingEnabled      bool   `json:"tracking_enabled"`
+	TrackingInterval     int    `json:"tracking_interval"`
+	PollingInterval      int    `json:"polling_interval"`
+	AccuracyMode         string `json:"accuracy_mode"`
+	MandatoryTracking    bool   `json:"mandatory_tracking"`
+	BatteryLowThreshold  int    `json:"battery_low_threshold"`
+	BatteryStopThreshold int    `json:"battery_stop_threshold"`
+}
+
+// Tracking control actions
+const (
+	TrackingActionStart = "start"
+	TrackingActionStop  = "stop"
+)
+
+// TrackingControlLog records a request to start or stop a user's tracking
+type TrackingControlLog struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index:idx_tracking_control_logs_user_created" json:"user_id"`
+	ChangedBy     uuid.UUID `gorm:"type:uuid;not null" json:"changed_by"`
+	PreviousState bool      `gorm:"not null" json:"previous_state"`
+	NewState      bool      `gorm:"not null" json:"new_state"`
+	CreatedAt     time.Time `gorm:"type:timestamptz;default:current_timestamp;not null;index:idx_tracking_control_logs_user_created" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TrackingControlRequest is the request body for starting or stopping tracking
+type TrackingControlRequest struct {
+	Action string `json:"action" validate:"required"`
+}
+
+// SelfTrackingControlRequest is the request body for allowing or forbidding a
+// user to control their own tracking
+type SelfTrackingControlRequest struct {
+	Allowed *bool `json:"allowed" validate:"required"`
+}
+
+// SelfTrackingControlResponse reports whether a user may control their own
+// tracking and where that decision comes from
+type SelfTrackingControlResponse struct {
+	Allowed bool   `json:"allowed"`
+	Source  string `json:"source"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (l *TrackingControlLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsValidTrackingAction checks if the given value is a valid tracking control action
+func IsValidTrackingAction(action string) bool {
+	return action == TrackingActionStart || action == TrackingActionStop
+}
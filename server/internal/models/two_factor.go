@@ -0,0 +1,142 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TwoFactorSecret is the TOTP secret of a user. It is pending until the user
+// proves their authenticator app works by entering a first code.
+type TwoFactorSecret struct {
+	UserID       uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Secret       string     `gorm:"type:varchar(64);not null" json:"-"`
+	ConfirmedAt  *time.Time `gorm:"type:timestamptz" json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `gorm:"default:0;not null" json:"-"` // codes of this step and earlier are rejected
+	CreatedAt    time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator app is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);not null" json:"-"`
+	UsedAt    *time.Time `gorm:"type:timestamptz" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// LoginChallenge is handed out when the password was correct but a second
+// factor is still needed; no session exists until it is answered
+type LoginChallenge struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash  string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ClientType string    `gorm:"type:varchar(20);not null" json:"client_type"`
+	Attempts   int       `gorm:"default:0;not null" json:"attempts"`
+	ExpiresAt  time.Time `gorm:"type:timestamptz;not null" json:"expires_at"`
+	CreatedAt  time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TwoFactorChallengeResponse is returned by login instead of a session when
+// a second factor is needed
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	SetupRequired     bool      `json:"setup_required"` // the user has to enroll before logging in
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// LoginChallengeRequest identifies a pending login challenge
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+// LoginTwoFactorRequest answers a login challenge with either a TOTP code or
+// a recovery code
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorSetupResponse carries a new TOTP secret for the authenticator app
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as QR code
+}
+
+// TwoFactorCodeRequest is the request body for actions confirmed with a
+// TOTP code or a recovery code
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// DisableTwoFactorRequest is the request body for turning off two-factor
+// authentication
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// RecoveryCodesResponse shows newly generated recovery codes; they cannot be
+// retrieved again
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatus describes the two-factor state of the current user
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (l *LoginChallenge) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsUsable checks if the challenge can still be answered
+func (l *LoginChallenge) IsUsable(now time.Time, maxAttempts int) bool {
+	return now.Before(l.ExpiresAt) && l.Attempts < maxAttempts
+}
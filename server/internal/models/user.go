@@ -23,21 +23,62 @@ import (
 	"gorm.io/gorm"
 )
 
+// Roles a user can have
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	GroupID      uuid.UUID `gorm:"type:uuid;not null" json:"group_id"`
-	Username     string    `gorm:"type:varchar(100);unique;not null" json:"username"`
-	Email        string    `gorm:"type:varchar(255)" json:"email"`
-	PasswordHash string    `gorm:"type:varchar(255);not null" json:"-"`
-	Role         string    `gorm:"type:varchar(50);default:'member';not null" json:"role"` // 'admin' or 'member'
-	CreatedAt    time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
-	
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GroupID          uuid.UUID      `gorm:"type:uuid;not null" json:"group_id"`
+	Username         string         `gorm:"type:varchar(100);unique;not null" json:"username"`
+	Email            string         `gorm:"type:varchar(255)" json:"email"`
+	PasswordHash     string         `gorm:"type:varchar(255);not null" json:"-"`
+	Role             string         `gorm:"type:varchar(50);default:'user';not null" json:"role"` // 'admin' or 'user'
+	FullName         string         `gorm:"type:varchar(255)" json:"full_name"`
+	Phone            string         `gorm:"type:varchar(50)" json:"phone"`
+	JobTitle         string         `gorm:"type:varchar(100)" json:"job_title"`
+	IsActive         bool           `gorm:"default:true;not null" json:"is_active"`
+	FirstLogin       bool           `gorm:"default:false;not null" json:"first_login"` // password has to be changed on next login
+	TwoFactorEnabled bool           `gorm:"column:two_factor_enabled;default:false;not null" json:"two_factor_enabled"`
+	OIDCSubject      *string        `gorm:"column:oidc_subject;type:varchar(255);uniqueIndex" json:"-"` // subject at the single sign-on identity provider
+	CreatedAt        time.Time      `gorm:"type:timestamptz;default:current_timestamp;not null" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"type:timestamptz;default:current_timestamp;not null" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"type:timestamptz;index" json:"deleted_at"`
+
 	// Relations
 	Group     Group      `gorm:"foreignKey:GroupID" json:"group,omitempty"`
 	Locations []Location `gorm:"foreignKey:UserID" json:"locations,omitempty"`
 }
 
+type UpdateRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// CreateUserRequest is the request body for creating a user
+type CreateUserRequest struct {
+	Username string     `json:"username" validate:"required"`
+	Email    string     `json:"email" validate:"required"`
+	Password string     `json:"password"` // temporary password; generated when empty
+	FullName string     `json:"full_name" validate:"required"`
+	Phone    string     `json:"phone"`
+	JobTitle string     `json:"job_title"`
+	GroupID  *uuid.UUID `json:"group_id"` // defaults to the creator's group
+	Role     string     `json:"role"`     // defaults to 'user'
+}
+
+// UpdateUserRequest is the request body for changing a user; omitted fields
+// are left unchanged
+type UpdateUserRequest struct {
+	Email    *string    `json:"email"`
+	FullName *string    `json:"full_name"`
+	Phone    *string    `json:"phone"`
+	JobTitle *string    `json:"job_title"`
+	GroupID  *uuid.UUID `json:"group_id"`
+	IsActive *bool      `json:"is_active"`
+}
+
 // BeforeCreate will set a UUID rather than numeric ID
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
@@ -46,7 +87,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// IsValidRole checks if the given value is a known role
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser
+}
+
 // IsAdmin checks if the user has admin role
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
-}
\ No newline at end of file
+	return u.Role == RoleAdmin
+}
@@ -0,0 +1,33 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldErrors are the problems of a request keyed by JSON field name
+type FieldErrors map[string]FieldError
+
+// ValidationErrorResponse is the body of a 400 response for a request with
+// invalid fields
+type ValidationErrorResponse struct {
+	Error  string      `json:"error"`
+	Fields FieldErrors `json:"fields"`
+}
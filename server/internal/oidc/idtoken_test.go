@@ -0,0 +1,133 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/oidc/idtoken_test.go
+
+package oidc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+
+	tests := []struct {
+		name   string
+		alg    string
+		kid    string
+		modify func(claims map[string]interface{})
+		token  func(token string) string
+		valid  bool
+	}{
+		{name: "RS256", alg: "RS256", kid: "rsa", valid: true},
+		{name: "PS256", alg: "PS256", kid: "rsa", valid: true},
+		{name: "ES256", alg: "ES256", kid: "ec", valid: true},
+		{
+			name:   "audience list with authorized party",
+			alg:    "RS256",
+			kid:    "rsa",
+			modify: func(c map[string]interface{}) { c["aud"] = []string{testClientID, "other"}; c["azp"] = testClientID },
+			valid:  true,
+		},
+		{
+			name:   "audience list without authorized party",
+			alg:    "RS256",
+			kid:    "rsa",
+			modify: func(c map[string]interface{}) { c["aud"] = []string{testClientID, "other"} },
+		},
+		{name: "wrong nonce", alg: "RS256", kid: "rsa", modify: func(c map[string]interface{}) { c["nonce"] = "other" }},
+		{name: "wrong audience", alg: "RS256", kid: "rsa", modify: func(c map[string]interface{}) { c["aud"] = "other" }},
+		{name: "wrong issuer", alg: "RS256", kid: "rsa", modify: func(c map[string]interface{}) { c["iss"] = "https://evil.example" }},
+		{name: "missing subject", alg: "RS256", kid: "rsa", modify: func(c map[string]interface{}) { delete(c, "sub") }},
+		{
+			name:   "expired",
+			alg:    "RS256",
+			kid:    "rsa",
+			modify: func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+		},
+		{
+			name:   "issued in the future",
+			alg:    "RS256",
+			kid:    "rsa",
+			modify: func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		},
+		{name: "unknown key", alg: "RS256", kid: "other"},
+		{name: "encryption key", alg: "RS256", kid: "enc"},
+		{name: "algorithm does not match key", alg: "ES256", kid: "rsa"},
+		{name: "HMAC with public key", alg: "HS256", kid: "rsa"},
+		{name: "alg none", alg: "none", kid: "rsa"},
+		{
+			name: "tampered claims",
+			alg:  "RS256",
+			kid:  "rsa",
+			token: func(token string) string {
+				parts := strings.Split(token, ".")
+				claims := idp.claims("nonce-1")
+				claims["sub"] = "admin"
+				return parts[0] + "." + encodeSegment(claims) + "." + parts[2]
+			},
+		},
+		{
+			name:  "missing signature",
+			alg:   "RS256",
+			kid:   "rsa",
+			token: func(token string) string { return token[:strings.LastIndex(token, ".")+1] },
+		},
+		{name: "malformed", alg: "RS256", kid: "rsa", token: func(string) string { return "not-a-jwt" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.claims("nonce-1")
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			token := idp.sign(t, tt.alg, tt.kid, claims)
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+
+			got, err := idp.provider().VerifyIDToken(context.Background(), token, "nonce-1")
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("VerifyIDToken: %v", err)
+				}
+				if got.Subject != "user-123" {
+					t.Errorf("subject = %q", got.Subject)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("VerifyIDToken error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenWithoutKeyID(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider()
+
+	// with two signing keys published the key ID is required
+	token := idp.sign(t, "RS256", "", idp.claims("nonce-1"))
+	if _, err := p.VerifyIDToken(context.Background(), token, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken error = %v, want ErrInvalidIDToken", err)
+	}
+}
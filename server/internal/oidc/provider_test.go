@@ -0,0 +1,353 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/oidc/provider_test.go
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "life-beacon"
+	testClientSecret = "s3cret/with+chars"
+	testRedirectURL  = "http://localhost:8080/api/auth/oidc/callback"
+)
+
+// testIdP is a minimal OpenID Connect provider serving discovery, JWKS and a
+// token endpoint that checks the PKCE verifier and client credentials
+type testIdP struct {
+	server *httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]pendingCode
+}
+
+// pendingCode is an authorization code waiting to be exchanged
+type pendingCode struct {
+	challenge string
+	nonce     string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &testIdP{rsaKey: rsaKey, ecKey: ecKey, codes: map[string]pendingCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                           idp.issuer(),
+			"authorization_endpoint":           idp.issuer() + "/authorize",
+			"token_endpoint":                   idp.issuer() + "/token",
+			"jwks_uri":                         idp.issuer() + "/jwks",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa",
+					"use": "sig",
+					"n":   encodeBigInt(rsaKey.N),
+					"e":   encodeBigInt(big.NewInt(int64(rsaKey.E))),
+				},
+				{
+					"kty": "EC",
+					"kid": "ec",
+					"use": "sig",
+					"crv": "P-256",
+					"x":   encodeBigInt(ecKey.X),
+					"y":   encodeBigInt(ecKey.Y),
+				},
+				// encryption keys must be ignored
+				{"kty": "RSA", "kid": "enc", "use": "enc", "n": encodeBigInt(rsaKey.N), "e": "AQAB"},
+			},
+		})
+	})
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) issuer() string {
+	return idp.server.URL
+}
+
+// provider returns a confidential client configured for the test IdP
+func (idp *testIdP) provider() *Provider {
+	return &Provider{
+		IssuerURL:    idp.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+		HTTPClient:   idp.server.Client(),
+	}
+}
+
+// authorize stands in for the login page: it accepts the parameters of an
+// authorization URL and returns the code the browser would be redirected with
+func (idp *testIdP) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != testClientID || q.Get("redirect_uri") != testRedirectURL {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	code := base64.RawURLEncoding.EncodeToString([]byte(q.Get("state")))
+	idp.mu.Lock()
+	idp.codes[code] = pendingCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	// confidential clients use client_secret_basic, public ones only send
+	// their client_id
+	authenticated := r.PostForm.Get("client_id") == testClientID
+	if user, pass, ok := r.BasicAuth(); ok {
+		user, _ = url.QueryUnescape(user)
+		pass, _ = url.QueryUnescape(pass)
+		authenticated = user == testClientID && pass == testClientSecret
+	}
+	if !authenticated {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	pending, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != testRedirectURL ||
+		!ok || CodeChallenge(r.PostForm.Get("code_verifier")) != pending.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_grant",
+			"error_description": "code or verifier is invalid",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"id_token": idp.sign(nil, "RS256", "rsa", idp.claims(pending.nonce)),
+	})
+}
+
+// claims returns valid ID token claims for the test client
+func (idp *testIdP) claims(nonce string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            idp.issuer(),
+		"sub":            "user-123",
+		"aud":            testClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          "jane@example.com",
+		"email_verified": "true",
+		"name":           "Jane Doe",
+	}
+}
+
+// sign builds a compact JWS of the claims. The key is picked from alg unless
+// given explicitly.
+func (idp *testIdP) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signingInput := encodeSegment(header) + "." + encodeSegment(claims)
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		digest := sha256Digest(signingInput)
+		signature, err = rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, digest)
+	case "PS256":
+		digest := sha256Digest(signingInput)
+		signature, err = rsa.SignPSS(rand.Reader, idp.rsaKey, crypto.SHA256, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, idp.ecKey, sha256Digest(signingInput))
+		if err == nil {
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	case "HS256":
+		// an HMAC "signed" with the public key, as in key confusion attacks
+		signature = sha256Digest(signingInput + string(idp.rsaKey.N.Bytes()))
+	}
+	if err != nil && t != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func sha256Digest(s string) []byte {
+	h := crypto.SHA256.New()
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestDiscover(t *testing.T) {
+	idp := newTestIdP(t)
+
+	discovery, err := idp.provider().Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if discovery.TokenEndpoint != idp.issuer()+"/token" {
+		t.Errorf("token endpoint = %q", discovery.TokenEndpoint)
+	}
+
+	other := idp.provider()
+	other.IssuerURL = idp.issuer() + "/realms/other"
+	if _, err := other.Discover(context.Background()); err == nil {
+		t.Error("Discover accepted a document for another issuer")
+	}
+}
+
+func TestLoginFlow(t *testing.T) {
+	idp := newTestIdP(t)
+	ctx := context.Background()
+
+	for _, confidential := range []bool{true, false} {
+		p := idp.provider()
+		if !confidential {
+			p.ClientSecret = ""
+		}
+
+		verifier, err := NewCodeVerifier()
+		if err != nil {
+			t.Fatal(err)
+		}
+		authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+		if err != nil {
+			t.Fatalf("AuthCodeURL: %v", err)
+		}
+		code := idp.authorize(t, authURL)
+
+		rawIDToken, err := p.Exchange(ctx, code, verifier)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+
+		claims, err := p.VerifyIDToken(ctx, rawIDToken, "nonce-1")
+		if err != nil {
+			t.Fatalf("VerifyIDToken: %v", err)
+		}
+		if claims.Subject != "user-123" || claims.Email != "jane@example.com" || !bool(claims.EmailVerified) {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+
+		// codes are single use
+		if _, err := p.Exchange(ctx, code, verifier); err == nil {
+			t.Error("Exchange accepted a used code")
+		}
+	}
+}
+
+func TestExchangeErrors(t *testing.T) {
+	idp := newTestIdP(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		secret   string
+		verifier string
+		want     string
+	}{
+		{"wrong verifier", testClientSecret, "verifier-two", "invalid_grant"},
+		{"wrong client secret", "guess", "verifier-one", "invalid_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := idp.provider()
+			p.ClientSecret = tt.secret
+
+			authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-one")
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := idp.authorize(t, authURL)
+
+			_, err = p.Exchange(ctx, code, tt.verifier)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Exchange error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/api_key_repo.go
+
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// CreateAPIKey stores a new API key
+func CreateAPIKey(db *gorm.DB, key *models.APIKey) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		return RecordAudit(tx, audit.ActionAPIKeyCreate, audit.EntityAPIKey, &key.ID, nil, key)
+	})
+}
+
+// GetAPIKeyByHash retrieves an API key and its user by the hash of the key
+func GetAPIKeyByHash(db *gorm.DB, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := db.Preload("User").Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByID retrieves an API key by its ID
+func GetAPIKeyByID(db *gorm.DB, id uuid.UUID) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := db.First(&key, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys lists the API keys of a user, newest first, including revoked
+// and expired keys
+func ListAPIKeys(db *gorm.DB, userID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey revokes an API key; revoking an already revoked key is a no-op
+func RevokeAPIKey(db *gorm.DB, id uuid.UUID, revokedBy uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.APIKey{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"revoked_at": time.Now(),
+				"revoked_by": revokedBy,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return RecordAudit(tx, audit.ActionAPIKeyRevoke, audit.EntityAPIKey, &id,
+			map[string]interface{}{"revoked": false},
+			map[string]interface{}{"revoked": true})
+	})
+}
+
+// TouchAPIKey records when and from where an API key was last used
+func TouchAPIKey(db *gorm.DB, id uuid.UUID, now time.Time, ipAddress string) error {
+	return db.Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ipAddress,
+		}).Error
+}
@@ -0,0 +1,126 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/audit_repo.go
+
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows down the audit log entries returned by ListAuditLogs
+type AuditFilter struct {
+	UserID     *uuid.UUID
+	Action     string
+	EntityType string
+	EntityID   *uuid.UUID
+	From       *time.Time
+	To         *time.Time
+}
+
+// RecordAudit writes an audit log entry for a change. It should be called with
+// the transaction that performs the change so both are committed together;
+// the actor is taken from the context attached to the transaction.
+func RecordAudit(tx *gorm.DB, action, entityType string, entityID *uuid.UUID, before, after interface{}) error {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	actor := audit.ActorFrom(tx.Statement.Context)
+	entry := models.AuditLog{
+		UserID:     actor.UserID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    models.JSON(payload),
+		IPAddress:  actor.IPAddress,
+		UserAgent:  actor.UserAgent,
+	}
+	return tx.Create(&entry).Error
+}
+
+// ListAuditLogs retrieves a page of audit log entries matching the filter,
+// newest first, together with the total number of matching entries
+func ListAuditLogs(db *gorm.DB, filter AuditFilter, offset, limit int) ([]models.AuditLog, int64, error) {
+	var total int64
+	if err := auditQuery(db, filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditLog
+	err := auditQuery(db, filter).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// EachAuditLog calls fn for every audit log entry matching the filter, oldest
+// first, without loading the whole result set into memory
+func EachAuditLog(db *gorm.DB, filter AuditFilter, fn func(*models.AuditLog) error) error {
+	rows, err := auditQuery(db, filter).Order("created_at ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func auditQuery(db *gorm.DB, filter AuditFilter) *gorm.DB {
+	query := db.Model(&models.AuditLog{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
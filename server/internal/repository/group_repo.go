@@ -0,0 +1,294 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/group_repo.go
+
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrGroupNameTaken is returned when a group name is already in use
+	ErrGroupNameTaken = errors.New("group name is already taken")
+	// ErrDefaultGroup is returned when the default group would be deleted
+	ErrDefaultGroup = errors.New("the default group cannot be deleted")
+	// ErrGroupNotEmpty is returned when a group that still has users would be deleted
+	ErrGroupNotEmpty = errors.New("the group still has users")
+	// ErrGroupDeleted is returned when a user of a deleted group would be restored
+	ErrGroupDeleted = errors.New("the group of the user has been deleted")
+)
+
+// GetGroupByID retrieves a group by its ID
+func GetGroupByID(db *gorm.DB, id uuid.UUID) (*models.Group, error) {
+	var group models.Group
+	if err := db.First(&group, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetGroupByName retrieves a group by its name
+func GetGroupByName(db *gorm.DB, name string) (*models.Group, error) {
+	var group models.Group
+	if err := db.First(&group, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetDefaultGroup retrieves the group new users are placed in by default
+func GetDefaultGroup(db *gorm.DB) (*models.Group, error) {
+	var group models.Group
+	if err := db.First(&group, "is_default = ?", true).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups retrieves a page of groups ordered by name, optionally limited to
+// names containing search, together with the total number of matches. With
+// deleted set only soft-deleted groups are listed.
+func ListGroups(db *gorm.DB, search string, deleted bool, offset, limit int) ([]models.Group, int64, error) {
+	query := db.Model(&models.Group{})
+	if deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if search != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(search)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var groups []models.Group
+	if err := query.Order("name").Offset(offset).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+// CreateGroup stores a new group and records the creation. Group names are
+// unique regardless of case.
+func CreateGroup(db *gorm.DB, group *models.Group) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureGroupNameFree(tx, group.Name, uuid.Nil); err != nil {
+			return err
+		}
+
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionGroupCreate, audit.EntityGroup, &group.ID, nil, group)
+	})
+}
+
+// RenameGroup changes the name of a group
+func RenameGroup(db *gorm.DB, groupID uuid.UUID, name string) (*models.Group, error) {
+	var after models.Group
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before models.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&before, "id = ?", groupID).Error; err != nil {
+			return err
+		}
+
+		if err := ensureGroupNameFree(tx, name, groupID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&before).Update("name", name).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&after, "id = ?", groupID).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionGroupUpdate, audit.EntityGroup, &groupID, &before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// DeleteGroup soft-deletes a group. The default group and groups that still
+// have users are refused; settings and grants are kept until the group is
+// purged.
+func DeleteGroup(db *gorm.DB, groupID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var group models.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&group, "id = ?", groupID).Error; err != nil {
+			return err
+		}
+
+		if group.IsDefault {
+			return ErrDefaultGroup
+		}
+
+		// The group row lock keeps users from being moved in meanwhile
+		var members int64
+		if err := tx.Model(&models.User{}).Where("group_id = ?", groupID).Count(&members).Error; err != nil {
+			return err
+		}
+		if members > 0 {
+			return ErrGroupNotEmpty
+		}
+
+		if err := tx.Delete(&group).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionGroupDelete, audit.EntityGroup, &groupID, &group, nil)
+	})
+}
+
+// RestoreGroup brings back a soft-deleted group. It is refused with
+// ErrGroupNameTaken if another group has taken its name meanwhile.
+func RestoreGroup(db *gorm.DB, groupID uuid.UUID) (*models.Group, error) {
+	var group models.Group
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").
+			First(&group, "id = ?", groupID).Error; err != nil {
+			return err
+		}
+
+		if err := ensureGroupNameFree(tx, group.Name, groupID); err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&group).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionGroupRestore, audit.EntityGroup, &groupID, nil, &group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// PurgeDeletedGroups removes groups soft-deleted before the cutoff for good,
+// together with their settings and grants. Groups that still have users, even
+// deleted ones waiting to be purged, are kept for a later run.
+func PurgeDeletedGroups(db *gorm.DB, cutoff time.Time) (int64, error) {
+	var groups []models.Group
+	if err := db.Unscoped().
+		Select("id", "name").
+		Where("deleted_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM users WHERE users.group_id = groups.id)").
+		Find(&groups).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, group := range groups {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Delete(&models.Group{}, "id = ?", group.ID).Error; err != nil {
+				return err
+			}
+
+			return RecordAudit(tx, audit.ActionGroupPurge, audit.EntityGroup, &group.ID,
+				map[string]interface{}{"name": group.Name}, nil)
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// MoveUsers moves the given users into a group and returns how many of them
+// actually changed group. All users must exist. authorize is called with the
+// locked users inside the transaction; an error from it aborts the move and
+// is returned as is.
+func MoveUsers(db *gorm.DB, groupID uuid.UUID, userIDs []uuid.UUID, authorize func(tx *gorm.DB, users []models.User) error) (int64, error) {
+	var moved int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the target group so it cannot be deleted while users move in
+		var group models.Group
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			First(&group, "id = ?", groupID).Error; err != nil {
+			return err
+		}
+
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", userIDs).
+			Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) != len(userIDs) {
+			return gorm.ErrRecordNotFound
+		}
+		if err := authorize(tx, users); err != nil {
+			return err
+		}
+
+		previous := make(map[string]uuid.UUID, len(users))
+		var movedIDs []uuid.UUID
+		for _, user := range users {
+			if user.GroupID != groupID {
+				previous[user.ID.String()] = user.GroupID
+				movedIDs = append(movedIDs, user.ID)
+			}
+		}
+		if len(movedIDs) == 0 {
+			return nil
+		}
+
+		result := tx.Model(&models.User{}).Where("id IN ?", movedIDs).Update("group_id", groupID)
+		if result.Error != nil {
+			return result.Error
+		}
+		moved = result.RowsAffected
+
+		return RecordAudit(tx, audit.ActionGroupMoveUsers, audit.EntityGroup, &groupID,
+			map[string]interface{}{"previous_groups": previous},
+			map[string]interface{}{"moved_users": movedIDs})
+	})
+	return moved, err
+}
+
+// ensureGroupNameFree fails with ErrGroupNameTaken if another group than
+// exceptID already uses the name
+func ensureGroupNameFree(tx *gorm.DB, name string, exceptID uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&models.Group{}).
+		Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGroupNameTaken
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/invitation_repo.go
+
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvitationInvalid is returned when an invitation is unknown, expired or
+// has already been accepted
+var ErrInvitationInvalid = errors.New("invitation is invalid or has expired")
+
+// CreateInvitation stores a new invitation for a user and discards any
+// earlier one. A non-empty passwordHash replaces the user's password with a
+// new temporary one and puts them back into the first-login state.
+func CreateInvitation(db *gorm.DB, invitation *models.Invitation, passwordHash string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND accepted_at IS NULL", invitation.UserID).
+			Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+
+		if passwordHash != "" {
+			if err := tx.Model(&models.User{}).
+				Where("id = ?", invitation.UserID).
+				Updates(map[string]interface{}{
+					"password_hash": passwordHash,
+					"first_login":   true,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(invitation).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserInvite, audit.EntityUser, &invitation.UserID, nil,
+			map[string]interface{}{"expires_at": invitation.ExpiresAt})
+	})
+}
+
+// GetLatestInvitation retrieves the most recent invitation of a user
+func GetLatestInvitation(db *gorm.DB, userID uuid.UUID) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation sets the password of the invited user and marks the
+// invitation as used. Unknown, expired and used tokens as well as deleted or
+// deactivated users all yield ErrInvitationInvalid.
+func AcceptInvitation(db *gorm.DB, tokenHash string, passwordHash string) (*models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.Invitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if !invitation.IsPending(now) {
+			return ErrInvitationInvalid
+		}
+
+		if err := tx.First(&user, "id = ?", invitation.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationInvalid
+			}
+			return err
+		}
+		if !user.IsActive {
+			return ErrInvitationInvalid
+		}
+
+		if err := tx.Model(&invitation).Update("accepted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash": passwordHash,
+			"first_login":   false,
+		}).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserInviteAccept, audit.EntityUser, &user.ID, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -19,18 +19,236 @@
 package repository
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// SaveCoordinate saves a Coordinate record to the database
-func SaveCoordinate(db *gorm.DB, coord *models.Location) error {
-	return db.Create(coord).Error
+// SaveCoordinate saves a Coordinate record to the database unless it
+// duplicates a stored location by idempotency key, by client ID and recording
+// time, or by client ID and sequence number. For a duplicate coord.ID is set
+// to the stored location and created is false.
+func SaveCoordinate(db *gorm.DB, coord *models.Location) (created bool, err error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(coord)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	stored, err := findStoredLocations(db, coord.UserID, []models.Location{*coord})
+	if err != nil {
+		return false, err
+	}
+	for _, key := range locationDedupeKeys(coord) {
+		if id, ok := stored[key]; ok {
+			coord.ID = id
+			break
+		}
+	}
+	return false, nil
+}
+
+// SaveLocations stores a batch of locations of one user with a multi-row
+// insert in a single transaction, so either all of them are stored or none.
+// Locations that duplicate a stored location or an earlier one in the batch,
+// including those a concurrent upload of the same points stores meanwhile,
+// are skipped and returned by index with the ID of the location they
+// duplicate; the others get their new ID.
+func SaveLocations(db *gorm.DB, locations []models.Location) (map[int]uint, error) {
+	duplicates := map[int]uint{}
+	if len(locations) == 0 {
+		return duplicates, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		stored, err := findStoredLocations(tx, locations[0].UserID, locations)
+		if err != nil {
+			return err
+		}
+
+		// Locations without dedupe keys cannot conflict, so they are inserted
+		// apart from those a concurrent upload may have stored meanwhile
+		var keyless, keyed []int
+		firstIndex := map[string]int{} // dedupe key -> index of the first new location with it
+		sameAs := map[int]int{}        // index of a repeated location -> index of its first occurrence
+	next:
+		for i := range locations {
+			keys := locationDedupeKeys(&locations[i])
+			for _, key := range keys {
+				if id, ok := stored[key]; ok {
+					duplicates[i] = id
+					continue next
+				}
+				if first, ok := firstIndex[key]; ok {
+					sameAs[i] = first
+					continue next
+				}
+			}
+			for _, key := range keys {
+				firstIndex[key] = i
+			}
+			if len(keys) == 0 {
+				keyless = append(keyless, i)
+			} else {
+				keyed = append(keyed, i)
+			}
+		}
+
+		for _, indexes := range [][]int{keyless, keyed} {
+			if err := insertLocations(tx, locations, indexes, duplicates); err != nil {
+				return err
+			}
+		}
+		for i, first := range sameAs {
+			if id, ok := duplicates[first]; ok {
+				duplicates[i] = id
+			} else {
+				duplicates[i] = locations[first].ID
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return duplicates, nil
 }
 
-// GetLatestLocations retrieves the latest n locations from the database
-func GetLatestLocations(db *gorm.DB, limit int) ([]models.Location, error) {
+// insertLocations stores the locations at the given indexes with a multi-row
+// insert and sets their IDs. Rows skipped because a concurrent upload stored
+// the same location first are added to duplicates. The returned IDs are not
+// in the order of the batch then, so the locations are looked up again.
+func insertLocations(tx *gorm.DB, locations []models.Location, indexes []int, duplicates map[int]uint) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	rows := make([]models.Location, len(indexes))
+	for n, i := range indexes {
+		rows[n] = locations[i]
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows)
+	if result.Error != nil {
+		return result.Error
+	}
+	if int(result.RowsAffected) == len(rows) {
+		for n, i := range indexes {
+			locations[i].ID = rows[n].ID
+		}
+		return nil
+	}
+
+	inserted := map[uint]bool{}
+	for _, row := range rows {
+		if row.ID != 0 {
+			inserted[row.ID] = true
+		}
+	}
+	stored, err := findStoredLocations(tx, rows[0].UserID, rows)
+	if err != nil {
+		return err
+	}
+	for n, i := range indexes {
+		for _, key := range locationDedupeKeys(&rows[n]) {
+			if id, ok := stored[key]; ok {
+				if inserted[id] {
+					locations[i].ID = id
+				} else {
+					duplicates[i] = id
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// findStoredLocations looks up the stored locations of a user that share a
+// dedupe key with any of the given locations, by dedupe key
+func findStoredLocations(db *gorm.DB, userID uuid.UUID, locations []models.Location) (map[string]uint, error) {
+	var idempotencyKeys []string
+	var recorded, sequences [][]interface{}
+	for _, l := range locations {
+		if l.IdempotencyKey != nil {
+			idempotencyKeys = append(idempotencyKeys, *l.IdempotencyKey)
+		}
+		if l.ClientID != "" {
+			recorded = append(recorded, []interface{}{l.ClientID, l.RecordedAt})
+		}
+		if l.Sequence != nil {
+			sequences = append(sequences, []interface{}{l.ClientID, *l.Sequence})
+		}
+	}
+
+	var conditions []string
+	var args []interface{}
+	if len(idempotencyKeys) > 0 {
+		conditions = append(conditions, "idempotency_key IN ?")
+		args = append(args, idempotencyKeys)
+	}
+	if len(recorded) > 0 {
+		conditions = append(conditions, "(client_id, recorded_at) IN ?")
+		args = append(args, recorded)
+	}
+	if len(sequences) > 0 {
+		conditions = append(conditions, "(client_id, sequence) IN ?")
+		args = append(args, sequences)
+	}
+
+	stored := map[string]uint{}
+	if len(conditions) == 0 {
+		return stored, nil
+	}
+
+	var matches []models.Location
+	err := db.Select("id", "client_id", "recorded_at", "sequence", "idempotency_key").
+		Where("user_id = ?", userID).
+		Where("("+strings.Join(conditions, " OR ")+")", args...).
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		for _, key := range locationDedupeKeys(&matches[i]) {
+			stored[key] = matches[i].ID
+		}
+	}
+	return stored, nil
+}
+
+// locationDedupeKeys returns the keys under which the unique indexes on
+// locations consider two locations of a user the same
+func locationDedupeKeys(l *models.Location) []string {
+	var keys []string
+	if l.IdempotencyKey != nil {
+		keys = append(keys, "idempotency:"+*l.IdempotencyKey)
+	}
+	if l.ClientID != "" {
+		keys = append(keys, "recorded:"+l.ClientID+"|"+l.RecordedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if l.Sequence != nil {
+		keys = append(keys, "sequence:"+l.ClientID+"|"+strconv.FormatInt(*l.Sequence, 10))
+	}
+	return keys
+}
+
+// GetLatestLocations retrieves the n most recently recorded locations of the
+// users the scope covers
+func GetLatestLocations(db *gorm.DB, scope models.PermissionScope, limit int) ([]models.Location, error) {
+	query := db.Model(&models.Location{})
+	if !scope.All {
+		query = query.Where("user_id IN ? OR user_id IN (SELECT id FROM users WHERE group_id IN ?)",
+			nonEmpty(scope.UserIDs), nonEmpty(scope.GroupIDs))
+	}
+
 	var locations []models.Location
-	err := db.Order("created_at DESC").Limit(limit).Find(&locations).Error
+	err := query.Order("recorded_at DESC, id DESC").Limit(limit).Find(&locations).Error
 	return locations, err
 }
@@ -0,0 +1,126 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/login_throttle_repo.go
+
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetLoginThrottles retrieves the throttles of the given keys; keys without
+// recent failures have none
+func GetLoginThrottles(db *gorm.DB, keys ...string) ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	err := db.Where("key IN ?", keys).Find(&throttles).Error
+	return throttles, err
+}
+
+// RecordLoginFailure counts a failed login for a key. Failures older than
+// window are forgotten first. Reaching threshold locks the key for window and
+// records the lockout; userID identifies the account for account keys.
+func RecordLoginFailure(db *gorm.DB, key string, threshold int, window time.Duration, userID *uuid.UUID) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginThrottle{Key: key, LastFailureAt: now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&throttle, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		if throttle.LastFailureAt.Before(now.Add(-window)) {
+			throttle.Failures = 0
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = now
+
+		locked := throttle.Failures >= threshold
+		if locked {
+			until := now.Add(window)
+			throttle.LockedUntil = &until
+			throttle.Failures = 0
+		}
+
+		if err := tx.Save(&throttle).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		return RecordAudit(tx, audit.ActionLoginLockout, audit.EntityLoginThrottle, userID, nil,
+			map[string]interface{}{"key": key, "locked_until": throttle.LockedUntil})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// ClearLoginFailures forgets the failures of a key after a successful login.
+// A running lockout is kept.
+func ClearLoginFailures(db *gorm.DB, key string) error {
+	return db.Where("key = ? AND (locked_until IS NULL OR locked_until <= ?)", key, time.Now()).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+// ListLoginThrottles retrieves keys that are locked out or have failures
+// within window, most recent failure first
+func ListLoginThrottles(db *gorm.DB, window time.Duration) ([]models.LoginThrottle, error) {
+	now := time.Now()
+	var throttles []models.LoginThrottle
+	err := db.Where("locked_until > ? OR last_failure_at > ?", now, now.Add(-window)).
+		Order("last_failure_at DESC").
+		Find(&throttles).Error
+	return throttles, err
+}
+
+// UnlockLoginThrottle lifts the lockout of a key and forgets its failures.
+// It returns gorm.ErrRecordNotFound when the key has no throttle.
+func UnlockLoginThrottle(db *gorm.DB, key string, userID *uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var throttle models.LoginThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&throttle, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&throttle).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionLoginUnlock, audit.EntityLoginThrottle, userID, &throttle, nil)
+	})
+}
+
+// DeleteStaleLoginThrottles removes throttles whose last failure and lockout
+// both ended before cutoff
+func DeleteStaleLoginThrottles(db *gorm.DB, cutoff time.Time) error {
+	return db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, cutoff).
+		Delete(&models.LoginThrottle{}).Error
+}
@@ -0,0 +1,102 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/oidc_repo.go
+
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOIDCStateInvalid is returned when a single sign-on callback does not
+	// belong to a pending login, or the login took too long
+	ErrOIDCStateInvalid = errors.New("single sign-on login is invalid or has expired")
+
+	// ErrOIDCAlreadyLinked is returned when a user is already linked to a
+	// different single sign-on identity
+	ErrOIDCAlreadyLinked = errors.New("user is linked to a different identity")
+)
+
+// CreateOIDCLoginState stores a pending single sign-on login
+func CreateOIDCLoginState(db *gorm.DB, state *models.OIDCLoginState) error {
+	return db.Create(state).Error
+}
+
+// ConsumeOIDCLoginState removes and returns the pending login with the given
+// state hash, so each callback can only be completed once
+func ConsumeOIDCLoginState(db *gorm.DB, stateHash string) (*models.OIDCLoginState, error) {
+	var states []models.OIDCLoginState
+	result := db.Clauses(clause.Returning{}).Where("state_hash = ?", stateHash).Delete(&states)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(states) == 0 || time.Now().After(states[0].ExpiresAt) {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &states[0], nil
+}
+
+// DeleteExpiredOIDCLoginStates removes pending logins that expired before
+// cutoff
+func DeleteExpiredOIDCLoginStates(db *gorm.DB, cutoff time.Time) error {
+	return db.Where("expires_at < ?", cutoff).Delete(&models.OIDCLoginState{}).Error
+}
+
+// GetUserByOIDCSubject retrieves the user linked to a single sign-on
+// identity, including soft-deleted users
+func GetUserByOIDCSubject(db *gorm.DB, subject string) (*models.User, error) {
+	var user models.User
+	if err := db.Unscoped().First(&user, "oidc_subject = ?", subject).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUsersByEmail retrieves all users with the given email address, compared
+// case-insensitively, whether active or not
+func GetUsersByEmail(db *gorm.DB, email string) ([]models.User, error) {
+	var users []models.User
+	err := db.Where("LOWER(email) = ?", normalizeEmail(email)).Find(&users).Error
+	return users, err
+}
+
+// LinkOIDCSubject links a user to a single sign-on identity. Users already
+// linked to another identity are refused with ErrOIDCAlreadyLinked.
+func LinkOIDCSubject(db *gorm.DB, userID uuid.UUID, subject string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND oidc_subject IS NULL", userID).
+			Update("oidc_subject", subject)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOIDCAlreadyLinked
+		}
+
+		return RecordAudit(tx, audit.ActionUserOIDCLink, audit.EntityUser, &userID, nil,
+			map[string]interface{}{"oidc_subject": subject})
+	})
+}
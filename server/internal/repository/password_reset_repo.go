@@ -0,0 +1,155 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/password_reset_repo.go
+
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPasswordResetInvalid is returned when a reset token is unknown,
+	// expired or has already been used
+	ErrPasswordResetInvalid = errors.New("password reset token is invalid or has expired")
+
+	// ErrTooManyResetRequests is returned when an email address has used up
+	// its reset requests for the current window
+	ErrTooManyResetRequests = errors.New("too many password reset requests")
+)
+
+// RecordPasswordResetRequest records a reset request for an email address.
+// It fails with ErrTooManyResetRequests, without recording anything, when the
+// address already made max requests since the given time.
+func RecordPasswordResetRequest(db *gorm.DB, email, ipAddress string, since time.Time, max int) error {
+	email = normalizeEmail(email)
+
+	var count int64
+	if err := db.Model(&models.PasswordResetRequest{}).
+		Where("email = ? AND created_at > ?", email, since).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(max) {
+		return ErrTooManyResetRequests
+	}
+
+	return db.Create(&models.PasswordResetRequest{
+		Email:     email,
+		IPAddress: ipAddress,
+	}).Error
+}
+
+// GetActiveUsersByEmail retrieves the active users with the given email
+// address, compared case-insensitively
+func GetActiveUsersByEmail(db *gorm.DB, email string) ([]models.User, error) {
+	var users []models.User
+	err := db.Where("LOWER(email) = ? AND is_active = ?", normalizeEmail(email), true).
+		Find(&users).Error
+	return users, err
+}
+
+// CreatePasswordResetToken stores a new reset token for a user and discards
+// any earlier unused one
+func CreatePasswordResetToken(db *gorm.DB, token *models.PasswordResetToken) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// ConfirmPasswordReset sets a new password for the owner of a reset token,
+// marks the token as used and revokes all of the user's sessions. Unknown,
+// expired and used tokens as well as deleted or deactivated users all yield
+// ErrPasswordResetInvalid.
+func ConfirmPasswordReset(db *gorm.DB, tokenHash string, passwordHash string) (*models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var token models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPasswordResetInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if !token.IsUsable(now) {
+			return ErrPasswordResetInvalid
+		}
+
+		if err := tx.First(&user, "id = ?", token.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPasswordResetInvalid
+			}
+			return err
+		}
+		if !user.IsActive {
+			return ErrPasswordResetInvalid
+		}
+
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password_hash": passwordHash,
+			"first_login":   false,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := RecordAudit(tx, audit.ActionUserPasswordReset, audit.EntityUser, &user.ID, nil, nil); err != nil {
+			return err
+		}
+
+		_, err := RevokeUserSessions(tx, user.ID, uuid.Nil, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteStalePasswordResets removes reset tokens that expired and reset
+// requests made before cutoff
+func DeleteStalePasswordResets(db *gorm.DB, cutoff time.Time) error {
+	if err := db.Where("expires_at < ?", cutoff).
+		Delete(&models.PasswordResetToken{}).Error; err != nil {
+		return err
+	}
+	return db.Where("created_at < ?", cutoff).
+		Delete(&models.PasswordResetRequest{}).Error
+}
+
+// normalizeEmail trims and lowercases an email address for comparisons
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
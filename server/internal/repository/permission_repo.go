@@ -0,0 +1,160 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/permission_repo.go
+
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAdminPermission is returned when a permission is granted to an admin,
+// whose permissions are immutable
+var ErrAdminPermission = errors.New("permissions cannot be granted to admins")
+
+// PermissionFilter narrows down the permissions returned by ListPermissions
+type PermissionFilter struct {
+	UserID         *uuid.UUID
+	GroupID        *uuid.UUID
+	PermissionType string
+}
+
+// ListPermissions retrieves permissions matching the filter, newest first
+func ListPermissions(db *gorm.DB, filter PermissionFilter) ([]models.Permission, error) {
+	query := db.Model(&models.Permission{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.GroupID != nil {
+		query = query.Where("group_id = ?", *filter.GroupID)
+	}
+	if filter.PermissionType != "" {
+		query = query.Where("permission_type = ?", filter.PermissionType)
+	}
+
+	var permissions []models.Permission
+	err := query.Order("created_at DESC").Find(&permissions).Error
+	return permissions, err
+}
+
+// GetPermissionByID retrieves a permission by its ID
+func GetPermissionByID(db *gorm.DB, id uuid.UUID) (*models.Permission, error) {
+	var permission models.Permission
+	if err := db.First(&permission, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// CreatePermission stores a new grant. Grants to a user are refused while the
+// user is an admin; the user row is locked so a concurrent promotion cannot slip in.
+func CreatePermission(db *gorm.DB, permission *models.Permission) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if permission.UserID != nil {
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&user, "id = ?", *permission.UserID).Error; err != nil {
+				return err
+			}
+			if user.IsAdmin() {
+				return ErrAdminPermission
+			}
+		}
+
+		if err := tx.Create(permission).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionPermissionGrant, audit.EntityPermission, &permission.ID, nil, permission)
+	})
+}
+
+// DeletePermission removes a single grant
+func DeletePermission(db *gorm.DB, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var permission models.Permission
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&permission, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&permission).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionPermissionRevoke, audit.EntityPermission, &permission.ID, &permission, nil)
+	})
+}
+
+// DeleteUserPermissions removes every grant made directly to a user
+func DeleteUserPermissions(db *gorm.DB, userID uuid.UUID) (int64, error) {
+	return deletePermissions(db, "user_id", userID, audit.EntityUser)
+}
+
+// DeleteGroupPermissions removes every grant made to a group
+func DeleteGroupPermissions(db *gorm.DB, groupID uuid.UUID) (int64, error) {
+	return deletePermissions(db, "group_id", groupID, audit.EntityGroup)
+}
+
+// deletePermissions removes all grants of a user or group and records the
+// removed grants against that user or group
+func deletePermissions(db *gorm.DB, column string, subjectID uuid.UUID, entityType string) (int64, error) {
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var permissions []models.Permission
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(column+" = ?", subjectID).
+			Find(&permissions).Error; err != nil {
+			return err
+		}
+		if len(permissions) == 0 {
+			return nil
+		}
+
+		result := tx.Delete(&models.Permission{}, column+" = ?", subjectID)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		before := map[string]interface{}{"permissions": permissions}
+		return RecordAudit(tx, audit.ActionPermissionReset, entityType, &subjectID, before, nil)
+	})
+	return deleted, err
+}
+
+// ListUserPermissions retrieves the grants of a permission type made directly to a user
+func ListUserPermissions(db *gorm.DB, userID uuid.UUID, permissionType string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := db.Where("user_id = ? AND permission_type = ?", userID, permissionType).
+		Find(&permissions).Error
+	return permissions, err
+}
+
+// ListGroupPermissions retrieves the grants of a permission type made to a group
+func ListGroupPermissions(db *gorm.DB, groupID uuid.UUID, permissionType string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := db.Where("group_id = ? AND permission_type = ?", groupID, permissionType).
+		Find(&permissions).Error
+	return permissions, err
+}
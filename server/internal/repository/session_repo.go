@@ -0,0 +1,131 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/session_repo.go
+
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// CreateSession stores a new session
+func CreateSession(db *gorm.DB, session *models.Session) error {
+	return db.Create(session).Error
+}
+
+// GetActiveSession retrieves an active session by the hash of its token
+// together with its user
+func GetActiveSession(db *gorm.DB, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := db.Preload("User").
+		Where("token_hash = ? AND is_active = ?", tokenHash, true).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SessionExists checks if a token hash belongs to any session, active or not
+func SessionExists(db *gorm.DB, tokenHash string) (bool, error) {
+	var count int64
+	err := db.Model(&models.Session{}).Where("token_hash = ?", tokenHash).Count(&count).Error
+	return count > 0, err
+}
+
+// TouchSession updates the last activity timestamp of a session
+func TouchSession(db *gorm.DB, tokenHash string, at time.Time) error {
+	return db.Model(&models.Session{}).
+		Where("token_hash = ?", tokenHash).
+		Update("last_activity", at).Error
+}
+
+// DeactivateSession marks a session as inactive so its token is no longer accepted
+func DeactivateSession(db *gorm.DB, tokenHash string) error {
+	return db.Model(&models.Session{}).
+		Where("token_hash = ? AND is_active = ?", tokenHash, true).
+		Updates(map[string]interface{}{
+			"is_active":  false,
+			"revoked_at": time.Now(),
+		}).Error
+}
+
+// GetSessionByID retrieves a session by its public ID
+func GetSessionByID(db *gorm.DB, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	if err := db.First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveSessions retrieves the active sessions of a user, most recently used first
+func ListActiveSessions(db *gorm.DB, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := db.Where("user_id = ? AND is_active = ?", userID, true).
+		Order("last_activity DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession deactivates a session by its public ID and records who revoked it
+func RevokeSession(db *gorm.DB, id uuid.UUID, revokedBy uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND is_active = ?", id, true).
+			Updates(map[string]interface{}{
+				"is_active":  false,
+				"revoked_at": time.Now(),
+				"revoked_by": revokedBy,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return RecordAudit(tx, audit.ActionSessionRevoke, audit.EntitySession, &id,
+			map[string]interface{}{"is_active": true},
+			map[string]interface{}{"is_active": false})
+	})
+}
+
+// RevokeUserSessions deactivates all active sessions of a user except the
+// session with the given ID (pass uuid.Nil to revoke every session)
+func RevokeUserSessions(db *gorm.DB, userID uuid.UUID, exceptID uuid.UUID, revokedBy uuid.UUID) (int64, error) {
+	var revoked int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("user_id = ? AND is_active = ? AND id <> ?", userID, true, exceptID).
+			Updates(map[string]interface{}{
+				"is_active":  false,
+				"revoked_at": time.Now(),
+				"revoked_by": revokedBy,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+
+		return RecordAudit(tx, audit.ActionSessionRevokeAll, audit.EntityUser, &userID,
+			nil, map[string]interface{}{"revoked_sessions": revoked})
+	})
+	return revoked, err
+}
@@ -0,0 +1,209 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/settings_repo.go
+
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetGlobalSettings retrieves the global settings row
+func GetGlobalSettings(db *gorm.DB) (*models.GlobalSettings, error) {
+	var global models.GlobalSettings
+	if err := db.First(&global).Error; err != nil {
+		return nil, err
+	}
+	return &global, nil
+}
+
+// UpdateGlobalSettings changes global settings. Values are keyed by setting
+// name, which is also the column name, and must already be validated.
+func UpdateGlobalSettings(db *gorm.DB, values map[string]interface{}, modifiedBy *uuid.UUID) (*models.GlobalSettings, error) {
+	var after models.GlobalSettings
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before models.GlobalSettings
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before).Error; err != nil {
+			return err
+		}
+
+		updates := make(map[string]interface{}, len(values)+1)
+		for key, value := range values {
+			updates[key] = value
+		}
+		updates["last_modified_by"] = modifiedBy
+
+		if err := tx.Model(&models.GlobalSettings{}).Where("id = ?", before.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&after, "id = ?", before.ID).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionSettingsUpdate, audit.EntityGlobalSettings, &after.ID, &before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// GetGroupSettings retrieves the overrides of a group. A group without
+// overrides yields empty settings rather than an error.
+func GetGroupSettings(db *gorm.DB, groupID uuid.UUID) (*models.GroupSettings, error) {
+	groupSettings := models.GroupSettings{GroupID: groupID, Settings: models.SettingsMap{}}
+	err := db.Where("group_id = ?", groupID).First(&groupSettings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &groupSettings, nil
+}
+
+// UpdateGroupSettings sets and clears overrides of a group. Values must
+// already be validated; clear lists keys to remove (nil removes none) and
+// clearAll removes every override before values are applied.
+func UpdateGroupSettings(db *gorm.DB, groupID uuid.UUID, values map[string]interface{}, clear []string, clearAll bool, modifiedBy *uuid.UUID) (*models.GroupSettings, error) {
+	var groupSettings models.GroupSettings
+	err := db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockGroupSettings(tx, groupID)
+		if err != nil {
+			return err
+		}
+		groupSettings = *locked
+
+		before := groupSettings.Settings
+		groupSettings.Settings = mergeOverrides(before, values, clear, clearAll)
+		groupSettings.LastModifiedBy = modifiedBy
+
+		if err := tx.Model(&groupSettings).Updates(map[string]interface{}{
+			"settings":         groupSettings.Settings,
+			"last_modified_by": modifiedBy,
+			"updated_at":       time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionSettingsUpdate, audit.EntityGroupSettings, &groupID,
+			map[string]interface{}(before), map[string]interface{}(groupSettings.Settings))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &groupSettings, nil
+}
+
+// GetUserSettings retrieves the overrides of a user. A user without
+// overrides yields empty settings rather than an error.
+func GetUserSettings(db *gorm.DB, userID uuid.UUID) (*models.UserSettings, error) {
+	userSettings := models.UserSettings{UserID: userID, Settings: models.SettingsMap{}, TrackingEnabled: true}
+	err := db.Where("user_id = ?", userID).First(&userSettings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &userSettings, nil
+}
+
+// UpdateUserSettings sets and clears overrides of a user, see UpdateGroupSettings
+func UpdateUserSettings(db *gorm.DB, userID uuid.UUID, values map[string]interface{}, clear []string, clearAll bool, modifiedBy *uuid.UUID) (*models.UserSettings, error) {
+	var userSettings models.UserSettings
+	err := db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockUserSettings(tx, userID)
+		if err != nil {
+			return err
+		}
+		userSettings = *locked
+
+		before := userSettings.Settings
+		now := time.Now()
+		userSettings.Settings = mergeOverrides(before, values, clear, clearAll)
+		userSettings.LastModifiedBy = modifiedBy
+		userSettings.LastModifiedAt = &now
+
+		if err := tx.Model(&userSettings).Updates(map[string]interface{}{
+			"settings":         userSettings.Settings,
+			"last_modified_by": modifiedBy,
+			"last_modified_at": now,
+			"updated_at":       now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionSettingsUpdate, audit.EntityUserSettings, &userID,
+			map[string]interface{}(before), map[string]interface{}(userSettings.Settings))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &userSettings, nil
+}
+
+// lockGroupSettings loads the settings row of a group for update, creating it
+// first if needed so that concurrent updates serialize on the same row
+func lockGroupSettings(tx *gorm.DB, groupID uuid.UUID) (*models.GroupSettings, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.GroupSettings{GroupID: groupID, Settings: models.SettingsMap{}}).Error; err != nil {
+		return nil, err
+	}
+
+	var groupSettings models.GroupSettings
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ?", groupID).
+		First(&groupSettings).Error; err != nil {
+		return nil, err
+	}
+	return &groupSettings, nil
+}
+
+// lockUserSettings loads the settings row of a user for update, creating it first if needed
+func lockUserSettings(tx *gorm.DB, userID uuid.UUID) (*models.UserSettings, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserSettings{UserID: userID, Settings: models.SettingsMap{}, TrackingEnabled: true}).Error; err != nil {
+		return nil, err
+	}
+
+	var userSettings models.UserSettings
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		First(&userSettings).Error; err != nil {
+		return nil, err
+	}
+	return &userSettings, nil
+}
+
+// mergeOverrides returns a copy of current with the given keys cleared and values applied
+func mergeOverrides(current models.SettingsMap, values map[string]interface{}, clear []string, clearAll bool) models.SettingsMap {
+	merged := models.SettingsMap{}
+	if !clearAll {
+		for key, value := range current {
+			merged[key] = value
+		}
+	}
+	for _, key := range clear {
+		delete(merged, key)
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,96 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/setup_repo.go
+
+package repository
+
+import (
+	"errors"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+)
+
+// setupAdvisoryLockKey serializes concurrent setup attempts across connections
+const setupAdvisoryLockKey = 360001
+
+// ErrSetupCompleted is returned when setup has already been performed
+var ErrSetupCompleted = errors.New("setup has already been completed")
+
+// IsSetupRequired reports whether the first-run setup still has to be performed.
+// Setup is only available while it has never been completed and no users exist.
+func IsSetupRequired(db *gorm.DB) (bool, error) {
+	var states int64
+	if err := db.Model(&models.SetupState{}).Count(&states).Error; err != nil {
+		return false, err
+	}
+	if states > 0 {
+		return false, nil
+	}
+
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return false, err
+	}
+	return users == 0, nil
+}
+
+// CompleteSetup creates the default group and the initial admin in a single
+// transaction and records setup as completed. Concurrent callers are
+// serialized by an advisory lock; all but the first get ErrSetupCompleted.
+func CompleteSetup(db *gorm.DB, group *models.Group, admin *models.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", setupAdvisoryLockKey).Error; err != nil {
+			return err
+		}
+
+		required, err := IsSetupRequired(tx)
+		if err != nil {
+			return err
+		}
+		if !required {
+			return ErrSetupCompleted
+		}
+
+		group.IsDefault = true
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+
+		admin.GroupID = group.ID
+		if err := tx.Create(admin).Error; err != nil {
+			return err
+		}
+
+		// The primary key guarantees a second row can never be inserted
+		if err := tx.Create(&models.SetupState{ID: models.SetupStateID, AdminID: admin.ID}).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionSetupComplete, audit.EntityUser, &admin.ID, nil,
+			map[string]interface{}{
+				"username":   admin.Username,
+				"email":      admin.Email,
+				"full_name":  admin.FullName,
+				"phone":      admin.Phone,
+				"role":       admin.Role,
+				"group_id":   group.ID,
+				"group_name": group.Name,
+			})
+	})
+}
@@ -0,0 +1,130 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/tracking_repo.go
+
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetTrackingEnabled starts or stops tracking for a user and records the
+// request in the tracking control log, even if the state did not change
+func SetTrackingEnabled(db *gorm.DB, userID uuid.UUID, enabled bool, changedBy uuid.UUID) (*models.TrackingControlLog, error) {
+	var entry models.TrackingControlLog
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userSettings, err := lockUserSettings(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(userSettings).Updates(map[string]interface{}{
+			"tracking_enabled":    enabled,
+			"tracking_changed_by": changedBy,
+			"tracking_changed_at": now,
+			"updated_at":          now,
+		}).Error; err != nil {
+			return err
+		}
+
+		entry = models.TrackingControlLog{
+			UserID:        userID,
+			ChangedBy:     changedBy,
+			PreviousState: userSettings.TrackingEnabled,
+			NewState:      enabled,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		action := audit.ActionTrackingStop
+		if enabled {
+			action = audit.ActionTrackingStart
+		}
+		return RecordAudit(tx, action, audit.EntityTracking, &userID,
+			map[string]interface{}{"tracking_enabled": entry.PreviousState},
+			map[string]interface{}{"tracking_enabled": entry.NewState})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListTrackingControlLogs retrieves a page of a user's tracking control log,
+// newest first, together with the total number of entries
+func ListTrackingControlLogs(db *gorm.DB, userID uuid.UUID, offset, limit int) ([]models.TrackingControlLog, int64, error) {
+	query := db.Model(&models.TrackingControlLog{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.TrackingControlLog
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// SetSelfTrackingControl allows or forbids a user to control their own
+// tracking by granting or revoking the user's own can_control_own_tracking
+// grant. Grants made to the user's group are left alone.
+func SetSelfTrackingControl(db *gorm.DB, userID uuid.UUID, allowed bool, grantedBy uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.Permission
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND permission_type = ?", userID, models.PermissionControlOwnTracking).
+			Find(&existing).Error; err != nil {
+			return err
+		}
+
+		for i := range existing {
+			if allowed && existing[i].TargetType == models.TargetSelf {
+				// Already allowed; keep the grant as it is
+				return nil
+			}
+		}
+
+		for i := range existing {
+			if err := tx.Delete(&existing[i]).Error; err != nil {
+				return err
+			}
+			if err := RecordAudit(tx, audit.ActionPermissionRevoke, audit.EntityPermission, &existing[i].ID, &existing[i], nil); err != nil {
+				return err
+			}
+		}
+
+		if !allowed {
+			return nil
+		}
+		return CreatePermission(tx, &models.Permission{
+			UserID:         &userID,
+			PermissionType: models.PermissionControlOwnTracking,
+			TargetType:     models.TargetSelf,
+			GrantedBy:      grantedBy,
+		})
+	})
+}
@@ -0,0 +1,196 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/two_factor_repo.go
+
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTwoFactorNotPending is returned when two-factor authentication is to be
+// confirmed but no enrollment was started
+var ErrTwoFactorNotPending = errors.New("two-factor enrollment has not been started")
+
+// GetTwoFactorSecret retrieves the TOTP secret of a user, pending or confirmed
+func GetTwoFactorSecret(db *gorm.DB, userID uuid.UUID) (*models.TwoFactorSecret, error) {
+	var secret models.TwoFactorSecret
+	if err := db.First(&secret, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// StartTwoFactorEnrollment stores a new pending TOTP secret for a user,
+// replacing any earlier pending one. It must not be called for users that
+// already have two-factor authentication enabled.
+func StartTwoFactorEnrollment(db *gorm.DB, userID uuid.UUID, secret string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"secret": secret, "created_at": time.Now()}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "two_factor_secrets.confirmed_at IS NULL"}}},
+	}).Create(&models.TwoFactorSecret{UserID: userID, Secret: secret}).Error
+}
+
+// EnableTwoFactor confirms the pending secret of a user with the step of the
+// first valid code and stores the hashes of a new set of recovery codes
+func EnableTwoFactor(db *gorm.DB, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TwoFactorSecret{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{
+				"confirmed_at":   time.Now(),
+				"last_used_step": step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorNotPending
+		}
+
+		if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUser2FAEnable, audit.EntityUser, &userID, nil, nil)
+	})
+}
+
+// DisableTwoFactor removes the TOTP secret and recovery codes of a user. The
+// action distinguishes users turning it off themselves from admin resets.
+func DisableTwoFactor(db *gorm.DB, userID uuid.UUID, action string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorSecret{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("two_factor_enabled", false).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, action, audit.EntityUser, &userID, nil, nil)
+	})
+}
+
+// UseTOTPStep marks a time step as used by a confirmed secret. It returns
+// false when that step or a later one was used already, so a code cannot be
+// replayed.
+func UseTOTPStep(db *gorm.DB, userID uuid.UUID, step int64) (bool, error) {
+	result := db.Model(&models.TwoFactorSecret{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UseRecoveryCode consumes an unused recovery code of a user. It returns
+// false when no such code exists.
+func UseRecoveryCode(db *gorm.DB, userID uuid.UUID, codeHash string) (bool, error) {
+	var used bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+			Update("used_at", time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		used = true
+
+		return RecordAudit(tx, audit.ActionUser2FARecoveryUse, audit.EntityUser, &userID, nil, nil)
+	})
+	return used, err
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user
+func RegenerateRecoveryCodes(db *gorm.DB, userID uuid.UUID, recoveryCodeHashes []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+			return err
+		}
+		return RecordAudit(tx, audit.ActionUser2FARecovery, audit.EntityUser, &userID, nil, nil)
+	})
+}
+
+// CountUnusedRecoveryCodes counts the recovery codes a user has left
+func CountUnusedRecoveryCodes(db *gorm.DB, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateLoginChallenge stores a challenge for the second login step
+func CreateLoginChallenge(db *gorm.DB, challenge *models.LoginChallenge) error {
+	return db.Create(challenge).Error
+}
+
+// GetLoginChallenge retrieves a login challenge by the hash of its token
+func GetLoginChallenge(db *gorm.DB, tokenHash string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	if err := db.First(&challenge, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// RecordLoginChallengeFailure counts a wrong answer to a login challenge
+func RecordLoginChallengeFailure(db *gorm.DB, id uuid.UUID) error {
+	return db.Model(&models.LoginChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// ConsumeLoginChallenge removes a login challenge once it has been answered.
+// It returns false when the challenge was consumed concurrently.
+func ConsumeLoginChallenge(db *gorm.DB, id uuid.UUID) (bool, error) {
+	result := db.Delete(&models.LoginChallenge{}, "id = ?", id)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteExpiredLoginChallenges removes login challenges that expired before
+// cutoff
+func DeleteExpiredLoginChallenges(db *gorm.DB, cutoff time.Time) error {
+	return db.Where("expires_at < ?", cutoff).Delete(&models.LoginChallenge{}).Error
+}
+
+// replaceRecoveryCodes swaps the recovery codes of a user for new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]models.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
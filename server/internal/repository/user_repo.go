@@ -0,0 +1,357 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/repository/user_repo.go
+
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/audit"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLastAdmin is returned when an operation would leave the system without an admin
+var ErrLastAdmin = errors.New("the last remaining admin cannot be removed or demoted")
+
+// ErrUsernameTaken is returned when a user is created with a username in use
+var ErrUsernameTaken = errors.New("username is already taken")
+
+// UserFilter narrows down the users returned by ListUsers
+type UserFilter struct {
+	GroupID  *uuid.UUID
+	Role     string
+	IsActive *bool
+	Search   string                  // matched against username, email and full name
+	Scope    *models.PermissionScope // when set, only users it covers are returned
+	Deleted  bool                    // list soft-deleted users instead of current ones
+}
+
+// GetUserByID retrieves a user by its ID
+func GetUserByID(db *gorm.DB, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := db.First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers retrieves a page of users matching the filter, ordered by
+// username, together with the total number of matches
+func ListUsers(db *gorm.DB, filter UserFilter, offset, limit int) ([]models.User, int64, error) {
+	query := db.Model(&models.User{})
+	if filter.Deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if filter.GroupID != nil {
+		query = query.Where("group_id = ?", *filter.GroupID)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		query = query.Where("username ILIKE ? OR email ILIKE ? OR full_name ILIKE ?", pattern, pattern, pattern)
+	}
+	if filter.Scope != nil && !filter.Scope.All {
+		query = query.Where("group_id IN ? OR id IN ?", nonEmpty(filter.Scope.GroupIDs), nonEmpty(filter.Scope.UserIDs))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	if err := query.Order("username").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetUserByUsername retrieves a user by its username
+func GetUserByUsername(db *gorm.DB, username string) (*models.User, error) {
+	var user models.User
+	if err := db.First(&user, "username = ?", username).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CountExistingUsers counts how many of the given IDs belong to existing users
+func CountExistingUsers(db *gorm.DB, ids []uuid.UUID) (int64, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("id IN ?", ids).Count(&count).Error
+	return count, err
+}
+
+// UpdateUserPasswordHash replaces the stored password hash of a user without
+// recording it; used to transparently upgrade hashes on login
+func UpdateUserPasswordHash(db *gorm.DB, id uuid.UUID, passwordHash string) error {
+	return db.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("password_hash", passwordHash).Error
+}
+
+// ChangeUserPassword sets a new password hash for a user, which also ends the
+// first-login state, and records the change
+func ChangeUserPassword(db *gorm.DB, id uuid.UUID, passwordHash string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"password_hash": passwordHash,
+				"first_login":   false,
+			}).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserPasswordChange, audit.EntityUser, &id, nil, nil)
+	})
+}
+
+// EnsureNotLastAdmin fails with ErrLastAdmin if the given user is the only
+// active admin left. It locks all active admin rows, so it must run inside the
+// transaction that removes, demotes or deactivates the user to be safe against
+// concurrent changes.
+func EnsureNotLastAdmin(tx *gorm.DB, userID uuid.UUID) error {
+	var admins []models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("role = ? AND is_active = ?", models.RoleAdmin, true).
+		Find(&admins).Error; err != nil {
+		return err
+	}
+
+	for _, admin := range admins {
+		if admin.ID == userID && len(admins) == 1 {
+			return ErrLastAdmin
+		}
+	}
+	return nil
+}
+
+// UpdateUserRole changes the role of a user. Demoting the last admin is
+// refused, and a user promoted to admin loses their individual grants since
+// admin permissions are immutable.
+func UpdateUserRole(db *gorm.DB, userID uuid.UUID, role string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		if user.Role == role {
+			return nil
+		}
+
+		if user.IsAdmin() {
+			if err := EnsureNotLastAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if role == models.RoleAdmin {
+			if _, err := DeleteUserPermissions(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("role", role).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserRoleChange, audit.EntityUser, &userID,
+			map[string]interface{}{"role": user.Role},
+			map[string]interface{}{"role": role})
+	})
+}
+
+// CreateUser stores a new user and records the creation
+func CreateUser(db *gorm.DB, user *models.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Deleted users keep their username until they are purged
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("username = ?", user.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUsernameTaken
+		}
+
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserCreate, audit.EntityUser, &user.ID, nil, user)
+	})
+}
+
+// UpdateUser applies changes to a user, keyed by column name. Deactivating
+// the last active admin is refused, and a deactivated user loses all sessions.
+func UpdateUser(db *gorm.DB, userID uuid.UUID, updates map[string]interface{}, changedBy uuid.UUID) (*models.User, error) {
+	var after models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&before, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		deactivate := updates["is_active"] == false && before.IsActive
+		if deactivate && before.IsAdmin() {
+			if err := EnsureNotLastAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&before).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&after, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		if deactivate {
+			if _, err := RevokeUserSessions(tx, userID, uuid.Nil, changedBy); err != nil {
+				return err
+			}
+		}
+
+		return RecordAudit(tx, audit.ActionUserUpdate, audit.EntityUser, &userID, &before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
+
+// DeleteUser soft-deletes a user and ends their sessions. Locations, grants
+// and settings are kept until the user is purged. Deleting the last active
+// admin is refused.
+func DeleteUser(db *gorm.DB, userID uuid.UUID, deletedBy uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		if user.IsAdmin() {
+			if err := EnsureNotLastAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := RevokeUserSessions(tx, userID, uuid.Nil, deletedBy); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserDelete, audit.EntityUser, &userID, &user, nil)
+	})
+}
+
+// RestoreUser brings back a soft-deleted user. A user whose group has been
+// deleted too is refused with ErrGroupDeleted until the group is restored.
+func RestoreUser(db *gorm.DB, userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").
+			First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		if _, err := GetGroupByID(tx, user.GroupID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGroupDeleted
+			}
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, audit.ActionUserRestore, audit.EntityUser, &userID, nil, &user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PurgeDeletedUsers removes users soft-deleted before the cutoff for good,
+// together with their locations. Sessions, grants, settings and the tracking
+// control log go with them through cascading foreign keys.
+func PurgeDeletedUsers(db *gorm.DB, cutoff time.Time) (int64, error) {
+	var users []models.User
+	if err := db.Unscoped().
+		Select("id", "username").
+		Where("deleted_at < ?", cutoff).
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, user := range users {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.Location{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&models.User{}, "id = ?", user.ID).Error; err != nil {
+				return err
+			}
+
+			return RecordAudit(tx, audit.ActionUserPurge, audit.EntityUser, &user.ID,
+				map[string]interface{}{"username": user.Username}, nil)
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// nonEmpty keeps an IN condition valid for an empty list; the nil UUID never
+// matches an existing row
+func nonEmpty(ids []uuid.UUID) []uuid.UUID {
+	if len(ids) == 0 {
+		return []uuid.UUID{uuid.Nil}
+	}
+	return ids
+}
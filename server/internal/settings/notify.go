@@ -0,0 +1,44 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/settings/notify.go
+
+package settings
+
+import "sync"
+
+// changes lets long-polling requests wait for settings to change
+var changes = struct {
+	sync.Mutex
+	ch chan struct{}
+}{ch: make(chan struct{})}
+
+// Changed returns a channel that is closed the next time settings change in
+// this process. Changes made by other server instances are not signalled, so
+// waiters should re-check periodically as well.
+func Changed() <-chan struct{} {
+	changes.Lock()
+	defer changes.Unlock()
+	return changes.ch
+}
+
+// NotifyChanged wakes everyone waiting on Changed
+func NotifyChanged() {
+	changes.Lock()
+	defer changes.Unlock()
+	close(changes.ch)
+	changes.ch = make(chan struct{})
+}
@@ -0,0 +1,126 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/settings/resolve.go
+
+package settings
+
+import (
+	"encoding/json"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Levels a setting value can come from, lowest precedence first
+const (
+	SourceGlobal = "global"
+	SourceGroup  = "group"
+	SourceUser   = "user"
+)
+
+// Value is the effective value of a setting and the level it came from
+type Value struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// Effective holds the resolved value of every setting for a user
+type Effective map[string]Value
+
+// Resolve computes the effective settings of a user: user overrides win over
+// group overrides, which win over the global settings
+func Resolve(db *gorm.DB, user *models.User) (Effective, error) {
+	global, err := repository.GetGlobalSettings(db)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := GlobalValues(global)
+	if err != nil {
+		return nil, err
+	}
+
+	effective := make(Effective, len(base))
+	for key, value := range base {
+		effective[key] = Value{Value: value, Source: SourceGlobal}
+	}
+
+	groupSettings, err := repository.GetGroupSettings(db, user.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	effective.apply(groupSettings.Settings, SourceGroup)
+
+	userSettings, err := repository.GetUserSettings(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	effective.apply(userSettings.Settings, SourceUser)
+
+	return effective, nil
+}
+
+// GlobalValues returns the global settings keyed by setting name
+func GlobalValues(global *models.GlobalSettings) (map[string]interface{}, error) {
+	b, err := json.Marshal(global)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// apply overlays overrides from a level. Keys that are no longer part of the
+// schema or cannot be overridden are ignored.
+func (e Effective) apply(overrides models.SettingsMap, source string) {
+	for key, value := range overrides {
+		if IsOverridable(key) {
+			e[key] = Value{Value: value, Source: source}
+		}
+	}
+}
+
+// Int returns an integer setting
+func (e Effective) Int(key string) int {
+	n, _ := toInt(e[key].Value)
+	return n
+}
+
+// OptionalInt returns a nullable integer setting
+func (e Effective) OptionalInt(key string) *int {
+	n, ok := toInt(e[key].Value)
+	if !ok {
+		return nil
+	}
+	return &n
+}
+
+// Bool returns a boolean setting
+func (e Effective) Bool(key string) bool {
+	b, _ := e[key].Value.(bool)
+	return b
+}
+
+// String returns a string setting
+func (e Effective) String(key string) string {
+	s, _ := e[key].Value.(string)
+	return s
+}
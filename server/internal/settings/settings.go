@@ -0,0 +1,206 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/settings/settings.go
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Setting keys; they match the JSON names of models.GlobalSettings
+const (
+	KeyTrackingInterval             = "tracking_interval"
+	KeyPollingInterval              = "polling_interval"
+	KeyAccuracyMode                 = "accuracy_mode"
+	KeyDataRetentionDays            = "data_retention_days"
+	KeySessionMaxDurationDays       = "session_max_duration_days"
+	KeySessionActivityExtensionDays = "session_activity_extension_days"
+	KeyBatteryLowThreshold          = "battery_low_threshold"
+	KeyBatteryStopThreshold         = "battery_stop_threshold"
+	KeyMandatoryTracking            = "mandatory_tracking"
+	KeyRequire2FAForAdmins          = "require_2fa_for_admins"
+	KeyLockoutThreshold             = "lockout_threshold"
+	KeyLockoutDurationMinutes       = "lockout_duration_minutes"
+	KeyIPLockoutThreshold           = "ip_lockout_threshold"
+)
+
+// Accuracy modes a client can be asked to track with
+const (
+	AccuracyHigh     = "high"
+	AccuracyBalanced = "balanced"
+	AccuracyLowPower = "low_power"
+)
+
+// TrackingIntervals are the tracking intervals, in seconds, clients support
+var TrackingIntervals = []int{60, 300, 600, 900, 1800, 3600}
+
+// definition describes how the value of a setting is validated
+type definition struct {
+	nullable   bool
+	globalOnly bool // cannot be overridden per group or user
+	validate   func(value interface{}) (interface{}, error)
+}
+
+var definitions = map[string]definition{
+	KeyTrackingInterval: {validate: oneOfInts(TrackingIntervals...)},
+	KeyPollingInterval:  {validate: intRange(10, 3600)},
+	KeyAccuracyMode:     {validate: oneOfStrings(AccuracyHigh, AccuracyBalanced, AccuracyLowPower)},
+	// null keeps location data forever
+	KeyDataRetentionDays:            {nullable: true, validate: intRange(1, 36500)},
+	KeySessionMaxDurationDays:       {validate: intRange(1, 365)},
+	KeySessionActivityExtensionDays: {validate: intRange(1, 365)},
+	KeyBatteryLowThreshold:          {validate: intRange(0, 100)},
+	KeyBatteryStopThreshold:         {validate: intRange(0, 100)},
+	KeyMandatoryTracking:            {validate: boolean},
+	KeyRequire2FAForAdmins:          {globalOnly: true, validate: boolean},
+	KeyLockoutThreshold:             {globalOnly: true, validate: intRange(1, 100)},
+	KeyLockoutDurationMinutes:       {globalOnly: true, validate: intRange(1, 1440)},
+	KeyIPLockoutThreshold:           {globalOnly: true, validate: intRange(1, 10000)},
+}
+
+// Keys returns all known setting keys in alphabetical order
+func Keys() []string {
+	keys := make([]string, 0, len(definitions))
+	for key := range definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsKnownKey checks if the given value is a known setting key
+func IsKnownKey(key string) bool {
+	_, ok := definitions[key]
+	return ok
+}
+
+// IsOverridable checks if the given key can be overridden per group or user
+func IsOverridable(key string) bool {
+	def, ok := definitions[key]
+	return ok && !def.globalOnly
+}
+
+// Validate checks a set of setting values against the schema. It returns the
+// values normalized to their canonical types (e.g. JSON numbers to int) and a
+// map of field errors keyed by setting name. Global-only settings are
+// rejected unless global is set.
+func Validate(values map[string]interface{}, global bool) (map[string]interface{}, map[string]string) {
+	normalized := make(map[string]interface{}, len(values))
+	fieldErrors := map[string]string{}
+
+	for key, value := range values {
+		def, ok := definitions[key]
+		if !ok {
+			fieldErrors[key] = "unknown setting"
+			continue
+		}
+		if def.globalOnly && !global {
+			fieldErrors[key] = "can only be set globally"
+			continue
+		}
+
+		if value == nil {
+			if !def.nullable {
+				fieldErrors[key] = "must not be null"
+				continue
+			}
+			normalized[key] = nil
+			continue
+		}
+
+		v, err := def.validate(value)
+		if err != nil {
+			fieldErrors[key] = err.Error()
+			continue
+		}
+		normalized[key] = v
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+	return normalized, nil
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, false
+		}
+		return int(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err == nil
+	}
+	return 0, false
+}
+
+func intRange(min, max int) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		n, ok := toInt(value)
+		if !ok {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if n < min || n > max {
+			return nil, fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return n, nil
+	}
+}
+
+func oneOfInts(allowed ...int) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		n, ok := toInt(value)
+		if ok {
+			for _, a := range allowed {
+				if n == a {
+					return n, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("must be one of %v", allowed)
+	}
+}
+
+func oneOfStrings(allowed ...string) func(interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if ok {
+			for _, a := range allowed {
+				if s == a {
+					return s, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("must be one of %v", allowed)
+	}
+}
+
+func boolean(value interface{}) (interface{}, error) {
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("must be a boolean")
+	}
+	return b, nil
+}
@@ -0,0 +1,169 @@
+// Life Beacon 360
+// Copyright (C) 2025 Tim Yashin/tiny-giraffes
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// internal/validation/location.go
+
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tiny-giraffes/life-beacon-360/server/internal/models"
+)
+
+// Codes of field errors, stable for clients to act on
+const (
+	CodeRequired      = "required"        // the field is missing
+	CodeNotANumber    = "not_a_number"    // NaN or infinite
+	CodeOutOfRange    = "out_of_range"    // outside the plausible range
+	CodeInFuture      = "in_future"       // later than the allowed clock skew
+	CodeTooOld        = "too_old"         // older than points are accepted
+	CodeTooLong       = "too_long"        // longer than allowed
+	CodeInvalidValue  = "invalid_value"   // not one of the allowed values
+	CodeTooManyPoints = "too_many_points" // batch exceeds MaxBatchSize
+	CodeEmpty         = "empty"           // batch without points
+	CodeInvalidJSON   = "invalid_json"    // the body is not a JSON object
+	CodeInvalidType   = "invalid_type"    // a value of the wrong JSON type
+)
+
+// BodyField is the key of errors about the request body as a whole
+const BodyField = "body"
+
+// Limits of accepted location data
+const (
+	MaxBatchSize     = 500                 // points in one batch upload
+	MaxFutureSkew    = 5 * time.Minute     // device clocks running ahead of the server
+	MaxPointAge      = 30 * 24 * time.Hour // points queued offline for longer are dropped
+	MaxAccuracy      = 100000.0            // meters
+	MinAltitude      = -1000.0             // meters, below the lowest land on earth
+	MaxAltitude      = 50000.0             // meters, above any aircraft
+	MaxSpeed         = 500.0               // meters per second, faster than any aircraft
+	MaxClientIDChars = 100
+)
+
+// Location checks a location request for missing, impossible and implausible
+// values. It returns nil if the request is valid.
+func Location(req *models.LocationRequest, now time.Time) models.FieldErrors {
+	errs := models.FieldErrors{}
+
+	checkRange(errs, "latitude", req.Latitude, -90, 90, true)
+	checkRange(errs, "longitude", req.Longitude, -180, 180, true)
+	checkRange(errs, "accuracy", req.Accuracy, 0, MaxAccuracy, false)
+	checkRange(errs, "altitude", req.Altitude, MinAltitude, MaxAltitude, false)
+	checkRange(errs, "speed", req.Speed, 0, MaxSpeed, false)
+	checkRange(errs, "bearing", req.Bearing, 0, 360, false)
+
+	if req.BatteryLevel != nil && (*req.BatteryLevel < 0 || *req.BatteryLevel > 100) {
+		add(errs, "battery_level", CodeOutOfRange, "must be between 0 and 100")
+	}
+
+	if req.RecordedAt != nil {
+		if req.RecordedAt.After(now.Add(MaxFutureSkew)) {
+			add(errs, "recorded_at", CodeInFuture, "must not be in the future")
+		} else if req.RecordedAt.Before(now.Add(-MaxPointAge)) {
+			add(errs, "recorded_at", CodeTooOld, fmt.Sprintf("must not be older than %d days", int(MaxPointAge.Hours()/24)))
+		}
+	}
+
+	if utf8.RuneCountInString(req.ClientID) > MaxClientIDChars {
+		add(errs, "client_id", CodeTooLong, fmt.Sprintf("must be at most %d characters", MaxClientIDChars))
+	}
+	if req.ClientType != "" && !models.IsValidClientType(req.ClientType) {
+		add(errs, "client_type", CodeInvalidValue, "must be web, mobile or desktop")
+	}
+	if req.Sequence != nil && *req.Sequence < 0 {
+		add(errs, "sequence", CodeOutOfRange, "must not be negative")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Batch checks the size of a batch upload. The points themselves are checked
+// one by one with Location, so a single bad point does not reject the batch.
+func Batch(req *models.LocationBatchRequest) models.FieldErrors {
+	switch {
+	case len(req.Locations) == 0:
+		return models.FieldErrors{"locations": {Code: CodeEmpty, Message: "must contain at least one location"}}
+	case len(req.Locations) > MaxBatchSize:
+		return models.FieldErrors{"locations": {Code: CodeTooManyPoints, Message: fmt.Sprintf("must contain at most %d locations", MaxBatchSize)}}
+	}
+	return nil
+}
+
+// BindError describes why a request body could not be decoded. A value of
+// the wrong JSON type is reported for its field, anything else for the body.
+func BindError(err error) models.FieldErrors {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return models.FieldErrors{typeErr.Field: {Code: CodeInvalidType, Message: "must be " + jsonType(typeErr.Type)}}
+	}
+	return models.FieldErrors{BodyField: {Code: CodeInvalidJSON, Message: "must be a valid JSON object"}}
+}
+
+// jsonType names the JSON type a Go type is decoded from
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC 3339 timestamp string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// checkRange checks that an optional number is a finite value within
+// [low, high]; required numbers must be present
+func checkRange(errs models.FieldErrors, field string, value *float64, low, high float64, required bool) {
+	switch {
+	case value == nil:
+		if required {
+			add(errs, field, CodeRequired, "is required")
+		}
+	case math.IsNaN(*value) || math.IsInf(*value, 0):
+		add(errs, field, CodeNotANumber, "must be a finite number")
+	case *value < low || *value > high:
+		add(errs, field, CodeOutOfRange, fmt.Sprintf("must be between %g and %g", low, high))
+	}
+}
+
+// add records the problem of a field
+func add(errs models.FieldErrors, field, code, message string) {
+	errs[field] = models.FieldError{Code: code, Message: message}
+}
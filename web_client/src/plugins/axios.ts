@@ -0,0 +1,18 @@
+/**
+ * plugins/axios.ts
+ *
+ * Configures axios for cookie-based sessions
+ */
+
+import axios from 'axios'
+
+// Send the httpOnly session cookie with every API request
+axios.defaults.withCredentials = true
+
+// Echo the CSRF cookie set at login back in the header the server checks
+// on state-changing requests
+axios.defaults.xsrfCookieName = 'lb360_csrf'
+axios.defaults.xsrfHeaderName = 'X-CSRF-Token'
+axios.defaults.withXSRFToken = true
+
+export default axios